@@ -2,14 +2,20 @@ package ast
 
 import (
 	"bytes"
+	"strconv"
 	"strings"
 	"zlang/token"
 )
 
 // Node requires every node to provide a token literal
+// and a source position. That is where the node starts,
+// except for assignments and the infix, call, index, slice,
+// member and propagate expressions, which report their
+// operator token so errors point at the operation itself
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 
 // Statement is a statement node
@@ -27,6 +33,7 @@ type Expression interface {
 // Program node is the root node of every AST
 type Program struct {
 	Statements []Statement
+	Comments   []*Comment // comments in source order, for tools
 }
 
 // TokenLiteral for Program node
@@ -37,6 +44,14 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+// Pos returns the position of the first statement in Program
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 // String returns String() from each node in Program
 func (p *Program) String() string {
 	var out bytes.Buffer
@@ -48,11 +63,27 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+// Comment is a // or /* */ comment node. Comments are not
+// statements, they are collected on the Program instead
+type Comment struct {
+	Token token.Token
+}
+
+// TokenLiteral returns token literal for comment
+func (c *Comment) TokenLiteral() string { return c.Token.Literal }
+
+// Pos returns the source position of the comment
+func (c *Comment) Pos() token.Position { return c.Token.Pos }
+
+// String returns the comment text including its delimiters
+func (c *Comment) String() string { return c.Token.Literal }
+
 // LetStatement is a let statement node (let a = 1;)
 type LetStatement struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token
+	Name    *Identifier
+	Pattern Expression // set instead of Name when destructuring
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -60,12 +91,19 @@ func (ls *LetStatement) statementNode() {}
 // TokenLiteral returns token literal for let statement
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 
+// Pos returns the source position of the let statement
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
+
 // String stringifies LetStatement node
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -76,6 +114,37 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// AssignStatement is an assignment node (x = 1; x += 1; arr[0] = 1; p.x = 1;).
+// Target is an *Identifier, an *IndexExpression or a *MemberExpression
+type AssignStatement struct {
+	Token    token.Token // the assignment operator token
+	Target   Expression
+	Operator string // =, +=, -=, *= or /=
+	Value    Expression
+}
+
+func (as *AssignStatement) statementNode() {}
+
+// TokenLiteral returns token literal for assign statement
+func (as *AssignStatement) TokenLiteral() string { return as.Token.Literal }
+
+// Pos returns the source position of the assignment operator
+func (as *AssignStatement) Pos() token.Position { return as.Token.Pos }
+
+// String stringifies an assign statement
+func (as *AssignStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(as.Target.String())
+	out.WriteString(" " + as.Operator + " ")
+	if as.Value != nil {
+		out.WriteString(as.Value.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
 // ReturnStatement is a return statement node
 type ReturnStatement struct {
 	Token       token.Token
@@ -87,6 +156,9 @@ func (rs *ReturnStatement) statementNode() {}
 // TokenLiteral returns a token literal for return statement
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 
+// Pos returns the source position of the return statement
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+
 // String stringifies a return statement
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
@@ -113,6 +185,9 @@ func (es *ExpressionStatement) statementNode() {}
 // TokenLiteral returns a token literal for expression statement
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
 
+// Pos returns the source position of the expression statement
+func (es *ExpressionStatement) Pos() token.Position { return es.Token.Pos }
+
 // String stringifies an expression statement
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
@@ -132,6 +207,9 @@ func (i *Identifier) expressionNode() {}
 // TokenLiteral returns a token literal for identifier
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 
+// Pos returns the source position of the identifier
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+
 // String returns identifier value
 func (i *Identifier) String() string { return i.Value }
 
@@ -146,9 +224,29 @@ func (il *IntegerLiteral) expressionNode() {}
 // TokenLiteral returns a token literal for integer literal
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 
+// Pos returns the source position of the integer literal
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+
 // String stringifies an integer literal
 func (il *IntegerLiteral) String() string { return il.Token.Literal }
 
+// FloatLiteral is a float literal node
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+
+// TokenLiteral returns a token literal for float literal
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+
+// Pos returns the source position of the float literal
+func (fl *FloatLiteral) Pos() token.Position { return fl.Token.Pos }
+
+// String stringifies a float literal
+func (fl *FloatLiteral) String() string { return fl.Token.Literal }
+
 // PrefixExpression is a prefix expression node
 type PrefixExpression struct {
 	Token    token.Token
@@ -161,13 +259,21 @@ func (pe *PrefixExpression) expressionNode() {}
 // TokenLiteral returns a token literal for prefix expression
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
 
+// Pos returns the source position of the prefix expression
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
+
 // String stringifies a prefix expression
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("(")
 	out.WriteString(pe.Operator)
-	out.WriteString(pe.Right.String())
+	if pe.Operator == "not" {
+		out.WriteString(" ")
+	}
+	if pe.Right != nil {
+		out.WriteString(pe.Right.String())
+	}
 	out.WriteString(")")
 
 	return out.String()
@@ -186,6 +292,9 @@ func (ie *InfixExpression) expressionNode() {}
 // TokenLiteral returns a token literal for infix expression
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos returns the source position of the infix operator
+func (ie *InfixExpression) Pos() token.Position { return ie.Token.Pos }
+
 // String stringifies an infix expression
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
@@ -210,6 +319,9 @@ func (b *Boolean) expressionNode() {}
 // TokenLiteral returns a token literal for boolean
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 
+// Pos returns the source position of the boolean
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
+
 // String stringifies a boolean
 func (b *Boolean) String() string { return b.Token.Literal }
 
@@ -226,6 +338,9 @@ func (ie *IfExpression) expressionNode() {}
 // TokenLiteral returns a token literal for if expression
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos returns the source position of the if expression
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+
 // String stringifies an if expression
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
@@ -253,6 +368,9 @@ func (bs *BlockStatement) statementNode() {}
 // TokenLiteral returns token literal for block statement
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
 
+// Pos returns the source position of the block statement
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+
 // String stringifies a block statement node
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
@@ -267,7 +385,10 @@ func (bs *BlockStatement) String() string {
 // FunctionLiteral is a function literal node
 type FunctionLiteral struct {
 	Token      token.Token
+	Name       string // set for fn name() {} declarations
 	Parameters []*Identifier
+	Defaults   []Expression // parallel to Parameters, nil where there is no default
+	Rest       *Identifier  // collects extra arguments, may be nil
 	Body       *BlockStatement
 }
 
@@ -276,16 +397,29 @@ func (fl *FunctionLiteral) expressionNode() {}
 // TokenLiteral returns a token literal for function literal
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 
+// Pos returns the source position of the function literal
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+
 // String stringifies a function literal node
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	for i, p := range fl.Parameters {
+		if i < len(fl.Defaults) && fl.Defaults[i] != nil {
+			params = append(params, p.String()+" = "+fl.Defaults[i].String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.String())
 	}
 
 	out.WriteString(fl.TokenLiteral())
+	if fl.Name != "" {
+		out.WriteString(" " + fl.Name)
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
@@ -294,6 +428,26 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// FunctionStatement is a named function declaration
+// (fn name(x) { ... }). Declarations are hoisted to the
+// top of the block they appear in
+type FunctionStatement struct {
+	Token    token.Token
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (fs *FunctionStatement) statementNode() {}
+
+// TokenLiteral returns a token literal for function statement
+func (fs *FunctionStatement) TokenLiteral() string { return fs.Token.Literal }
+
+// Pos returns the source position of the function statement
+func (fs *FunctionStatement) Pos() token.Position { return fs.Token.Pos }
+
+// String stringifies a function statement
+func (fs *FunctionStatement) String() string { return fs.Function.String() }
+
 // CallExpression is a call expression node
 type CallExpression struct {
 	Token     token.Token
@@ -306,6 +460,9 @@ func (ce *CallExpression) expressionNode() {}
 // TokenLiteral returns a token literal for call expression
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
 
+// Pos returns the source position of the ( of the call
+func (ce *CallExpression) Pos() token.Position { return ce.Token.Pos }
+
 // String stringifies a call expression node
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
@@ -334,8 +491,52 @@ func (sl *StringLiteral) expressionNode() {}
 // TokenLiteral returns a token literal for string literal
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 
-// String stringifies a string literal
-func (sl *StringLiteral) String() string { return sl.Token.Literal }
+// Pos returns the source position of the string literal
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Pos }
+
+// String stringifies a string literal as a quoted,
+// escaped literal that lexes back to the same value
+func (sl *StringLiteral) String() string { return `"` + escapeString(sl.Value) + `"` }
+
+// escapeString escapes s for use between double quotes,
+// including the ${ that would otherwise start an interpolation
+func escapeString(s string) string {
+	quoted := strconv.Quote(s)
+	return strings.ReplaceAll(quoted[1:len(quoted)-1], "${", `\${`)
+}
+
+// InterpolatedString is a string with embedded expressions
+// ("length is ${len(arr)}"). Parts holds the literal text as
+// StringLiterals and the embedded expressions in source order
+type InterpolatedString struct {
+	Token token.Token // the first STRING_PART token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode() {}
+
+// TokenLiteral returns a token literal for interpolated string
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+
+// Pos returns the source position of the interpolated string
+func (is *InterpolatedString) Pos() token.Position { return is.Token.Pos }
+
+// String stringifies an interpolated string
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(`"`)
+	for _, part := range is.Parts {
+		if str, ok := part.(*StringLiteral); ok {
+			out.WriteString(escapeString(str.Value))
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	out.WriteString(`"`)
+
+	return out.String()
+}
 
 // ArrayLiteral is an array node
 type ArrayLiteral struct {
@@ -348,6 +549,9 @@ func (al *ArrayLiteral) expressionNode() {}
 // TokenLiteral returns a token literal for array literal
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
 
+// Pos returns the source position of the array literal
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+
 // String stringifies an array literal
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
@@ -376,6 +580,9 @@ func (ie *IndexExpression) expressionNode() {}
 // TokenLiteral returns a token literal for index expression
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos returns the source position of the [ of the index
+func (ie *IndexExpression) Pos() token.Position { return ie.Token.Pos }
+
 // String stringifies an index expression
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
@@ -388,3 +595,619 @@ func (ie *IndexExpression) String() string {
 
 	return out.String()
 }
+
+// HashLiteral is a hash node ({"name": "bob", 1: true}).
+// Keys and Values are parallel and kept in source order
+type HashLiteral struct {
+	Token  token.Token // the '{' token
+	Keys   []Expression
+	Values []Expression
+}
+
+func (hl *HashLiteral) expressionNode() {}
+
+// TokenLiteral returns a token literal for hash literal
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+
+// Pos returns the source position of the hash literal
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
+
+// String stringifies a hash literal
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for i, key := range hl.Keys {
+		pairs = append(pairs, key.String()+": "+hl.Values[i].String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// WhileStatement is a while loop node
+type WhileStatement struct {
+	Token     token.Token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+
+// TokenLiteral returns a token literal for while statement
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+
+// Pos returns the source position of the while statement
+func (ws *WhileStatement) Pos() token.Position { return ws.Token.Pos }
+
+// String stringifies a while statement
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+// ForStatement is a C-style for loop node. Init, Condition
+// and Post are all optional and may be nil
+type ForStatement struct {
+	Token     token.Token
+	Init      Statement
+	Condition Expression
+	Post      Statement
+	Body      *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {}
+
+// TokenLiteral returns a token literal for for statement
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+
+// Pos returns the source position of the for statement
+func (fs *ForStatement) Pos() token.Position { return fs.Token.Pos }
+
+// String stringifies a for statement
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	parts := []string{"", "", ""}
+	if fs.Init != nil {
+		parts[0] = strings.TrimSuffix(fs.Init.String(), ";")
+	}
+	if fs.Condition != nil {
+		parts[1] = fs.Condition.String()
+	}
+	if fs.Post != nil {
+		parts[2] = strings.TrimSuffix(fs.Post.String(), ";")
+	}
+
+	out.WriteString("for (")
+	out.WriteString(strings.Join(parts, "; "))
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// BreakStatement is a break node
+type BreakStatement struct {
+	Token token.Token
+}
+
+func (bs *BreakStatement) statementNode() {}
+
+// TokenLiteral returns a token literal for break statement
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+
+// Pos returns the source position of the break statement
+func (bs *BreakStatement) Pos() token.Position { return bs.Token.Pos }
+
+// String stringifies a break statement
+func (bs *BreakStatement) String() string { return bs.TokenLiteral() + ";" }
+
+// ContinueStatement is a continue node
+type ContinueStatement struct {
+	Token token.Token
+}
+
+func (cs *ContinueStatement) statementNode() {}
+
+// TokenLiteral returns a token literal for continue statement
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+
+// Pos returns the source position of the continue statement
+func (cs *ContinueStatement) Pos() token.Position { return cs.Token.Pos }
+
+// String stringifies a continue statement
+func (cs *ContinueStatement) String() string { return cs.TokenLiteral() + ";" }
+
+// SwitchExpression is a switch expression node. It evaluates
+// to the value of the matched case, or of Default if present
+type SwitchExpression struct {
+	Token   token.Token
+	Subject Expression
+	Cases   []*SwitchCase
+	Default *BlockStatement
+}
+
+func (se *SwitchExpression) expressionNode() {}
+
+// TokenLiteral returns a token literal for switch expression
+func (se *SwitchExpression) TokenLiteral() string { return se.Token.Literal }
+
+// Pos returns the source position of the switch expression
+func (se *SwitchExpression) Pos() token.Position { return se.Token.Pos }
+
+// String stringifies a switch expression
+func (se *SwitchExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("switch")
+	out.WriteString(se.Subject.String())
+	out.WriteString(" { ")
+	for _, c := range se.Cases {
+		out.WriteString(c.String())
+		out.WriteString(" ")
+	}
+	if se.Default != nil {
+		out.WriteString("default { ")
+		out.WriteString(se.Default.String())
+		out.WriteString(" } ")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// SwitchCase is a single case of a switch expression,
+// matching when the subject equals any of its Values
+type SwitchCase struct {
+	Token  token.Token
+	Values []Expression
+	Body   *BlockStatement
+}
+
+// TokenLiteral returns a token literal for switch case
+func (sc *SwitchCase) TokenLiteral() string { return sc.Token.Literal }
+
+// Pos returns the source position of the switch case
+func (sc *SwitchCase) Pos() token.Position { return sc.Token.Pos }
+
+// String stringifies a switch case
+func (sc *SwitchCase) String() string {
+	var out bytes.Buffer
+
+	values := []string{}
+	for _, v := range sc.Values {
+		values = append(values, v.String())
+	}
+
+	out.WriteString("case ")
+	out.WriteString(strings.Join(values, ", "))
+	out.WriteString(" { ")
+	out.WriteString(sc.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// MatchExpression is a match expression node. Arms are tried
+// in order and the first whose pattern and guard match is taken
+type MatchExpression struct {
+	Token   token.Token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode() {}
+
+// TokenLiteral returns a token literal for match expression
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+
+// Pos returns the source position of the match expression
+func (me *MatchExpression) Pos() token.Position { return me.Token.Pos }
+
+// String stringifies a match expression
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, a := range me.Arms {
+		arms = append(arms, a.String())
+	}
+
+	out.WriteString("match ")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// MatchArm is a single pattern => body arm of a match
+// expression, with an optional if guard
+type MatchArm struct {
+	Token   token.Token
+	Pattern Expression
+	Guard   Expression
+	Body    *BlockStatement
+}
+
+// TokenLiteral returns a token literal for match arm
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+
+// Pos returns the source position of the match arm
+func (ma *MatchArm) Pos() token.Position { return ma.Token.Pos }
+
+// String stringifies a match arm
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+// ArrayPattern is a pattern matching arrays element by element.
+// Rest, if set, collects any elements past the last pattern
+type ArrayPattern struct {
+	Token    token.Token
+	Elements []Expression
+	Rest     *Identifier
+}
+
+func (ap *ArrayPattern) expressionNode() {}
+
+// TokenLiteral returns a token literal for array pattern
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+
+// Pos returns the source position of the array pattern
+func (ap *ArrayPattern) Pos() token.Position { return ap.Token.Pos }
+
+// String stringifies an array pattern
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// HashPattern is a pattern matching hashes, binding each
+// name to the value stored under the same string key
+type HashPattern struct {
+	Token token.Token
+	Keys  []*Identifier
+}
+
+func (hp *HashPattern) expressionNode() {}
+
+// TokenLiteral returns a token literal for hash pattern
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+
+// Pos returns the source position of the hash pattern
+func (hp *HashPattern) Pos() token.Position { return hp.Token.Pos }
+
+// String stringifies a hash pattern
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+
+	keys := []string{}
+	for _, key := range hp.Keys {
+		keys = append(keys, key.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(keys, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// MemberExpression is a field or method access node (obj.name)
+type MemberExpression struct {
+	Token    token.Token // the . token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode() {}
+
+// TokenLiteral returns a token literal for member expression
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+
+// Pos returns the source position of the . of the member access
+func (me *MemberExpression) Pos() token.Position { return me.Token.Pos }
+
+// String stringifies a member expression
+func (me *MemberExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(me.Object.String())
+	out.WriteString(".")
+	out.WriteString(me.Property.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// PropagateExpression is a postfix error check (value?)
+type PropagateExpression struct {
+	Token token.Token // the ? token
+	Value Expression
+}
+
+func (pe *PropagateExpression) expressionNode() {}
+
+// TokenLiteral returns a token literal for propagate expression
+func (pe *PropagateExpression) TokenLiteral() string { return pe.Token.Literal }
+
+// Pos returns the source position of the ? operator
+func (pe *PropagateExpression) Pos() token.Position { return pe.Token.Pos }
+
+// String stringifies a propagate expression
+func (pe *PropagateExpression) String() string {
+	return "(" + pe.Value.String() + "?)"
+}
+
+// SliceExpression is a slice node (a[start:end:step]).
+// Any of Start, End and Step may be nil when left out
+type SliceExpression struct {
+	Token token.Token // the [ token
+	Left  Expression
+	Start Expression
+	End   Expression
+	Step  Expression
+}
+
+func (se *SliceExpression) expressionNode() {}
+
+// TokenLiteral returns a token literal for slice expression
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+
+// Pos returns the source position of the [ of the slice
+func (se *SliceExpression) Pos() token.Position { return se.Token.Pos }
+
+// String stringifies a slice expression
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	if se.Step != nil {
+		out.WriteString(":")
+		out.WriteString(se.Step.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// ImportStatement is an import node (import "lib/strings.z" as strs;)
+type ImportStatement struct {
+	Token token.Token
+	Path  *StringLiteral
+	Name  *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+
+// TokenLiteral returns a token literal for import statement
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+
+// Pos returns the source position of the import statement
+func (is *ImportStatement) Pos() token.Position { return is.Token.Pos }
+
+// String stringifies an import statement
+func (is *ImportStatement) String() string {
+	return is.TokenLiteral() + " " + is.Path.String() + " as " + is.Name.String() + ";"
+}
+
+// StructStatement is a struct declaration (struct Point { x, y })
+type StructStatement struct {
+	Token  token.Token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructStatement) statementNode() {}
+
+// TokenLiteral returns a token literal for struct statement
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+
+// Pos returns the source position of the struct statement
+func (ss *StructStatement) Pos() token.Position { return ss.Token.Pos }
+
+// String stringifies a struct statement
+func (ss *StructStatement) String() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+
+	out.WriteString(ss.TokenLiteral() + " ")
+	out.WriteString(ss.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// EnumStatement is an enum declaration
+// (enum Shape { Circle(r), Rect(w, h), Empty })
+type EnumStatement struct {
+	Token    token.Token
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+func (es *EnumStatement) statementNode() {}
+
+// TokenLiteral returns a token literal for enum statement
+func (es *EnumStatement) TokenLiteral() string { return es.Token.Literal }
+
+// Pos returns the source position of the enum statement
+func (es *EnumStatement) Pos() token.Position { return es.Token.Pos }
+
+// String stringifies an enum statement
+func (es *EnumStatement) String() string {
+	var out bytes.Buffer
+
+	variants := []string{}
+	for _, v := range es.Variants {
+		variants = append(variants, v.String())
+	}
+
+	out.WriteString(es.TokenLiteral() + " ")
+	out.WriteString(es.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(variants, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// EnumVariant is a single variant of an enum. Fields is nil
+// for variants without a payload
+type EnumVariant struct {
+	Token  token.Token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+// TokenLiteral returns a token literal for enum variant
+func (ev *EnumVariant) TokenLiteral() string { return ev.Token.Literal }
+
+// Pos returns the source position of the enum variant
+func (ev *EnumVariant) Pos() token.Position { return ev.Token.Pos }
+
+// String stringifies an enum variant
+func (ev *EnumVariant) String() string {
+	if ev.Fields == nil {
+		return ev.Name.String()
+	}
+
+	fields := []string{}
+	for _, f := range ev.Fields {
+		fields = append(fields, f.String())
+	}
+
+	return ev.Name.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+// ThrowStatement is a throw statement node
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+// TokenLiteral returns a token literal for throw statement
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+
+// Pos returns the source position of the throw statement
+func (ts *ThrowStatement) Pos() token.Position { return ts.Token.Pos }
+
+// String stringifies a throw statement
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
+// DeferStatement is a defer statement node. Its expression
+// is evaluated when the enclosing function returns
+type DeferStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ds *DeferStatement) statementNode() {}
+
+// TokenLiteral returns a token literal for defer statement
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+
+// Pos returns the source position of the defer statement
+func (ds *DeferStatement) Pos() token.Position { return ds.Token.Pos }
+
+// String stringifies a defer statement
+func (ds *DeferStatement) String() string {
+	return ds.TokenLiteral() + " " + ds.Value.String() + ";"
+}
+
+// TryExpression is a try expression node. At least one of
+// Catch and Finally is set, and Param is optional
+// (try { } catch (e) { } finally { })
+type TryExpression struct {
+	Token   token.Token
+	Body    *BlockStatement
+	Param   *Identifier
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (te *TryExpression) expressionNode() {}
+
+// TokenLiteral returns a token literal for try expression
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+
+// Pos returns the source position of the try expression
+func (te *TryExpression) Pos() token.Position { return te.Token.Pos }
+
+// String stringifies a try expression
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Body.String())
+
+	if te.Catch != nil {
+		out.WriteString(" catch ")
+		if te.Param != nil {
+			out.WriteString("(" + te.Param.String() + ") ")
+		}
+		out.WriteString(te.Catch.String())
+	}
+
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
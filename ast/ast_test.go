@@ -26,3 +26,14 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestPrefixStringWithoutOperand(t *testing.T) {
+	pe := &PrefixExpression{
+		Token:    token.Token{Type: token.MINUS, Literal: "-"},
+		Operator: "-",
+	}
+
+	if pe.String() != "(-)" {
+		t.Errorf("pe.String() wrong. got=%q", pe.String())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 	"zlang/object"
 )
 
@@ -20,7 +21,9 @@ var builtins = map[string]*object.Builtin{
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *object.Hash:
+				return &object.Integer{Value: int64(len(arg.Keys))}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
@@ -69,13 +72,32 @@ var builtins = map[string]*object.Builtin{
 	},
 	"int": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if len(args) > 2 || len(args) < 1 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			// int(s, base) parses s in the given base, 0 means
+			// the base is taken from a 0x, 0o or 0b prefix
+			if len(args) == 2 {
+				str, ok := args[0].(*object.String)
+				if !ok {
+					return newError("argument to `int` with a base must be STRING, got %s", args[0].Type())
+				}
+				base, err := baseArgument(args[1], true)
+				if err != nil {
+					return err
+				}
+				if i, err := strconv.ParseInt(str.Value, base, 64); err == nil {
+					return &object.Integer{Value: i}
+				}
+				return newError("could not convert %q to INTEGER in base %d", str.Value, base)
 			}
 
 			switch arg := args[0].(type) {
 			case *object.Integer:
 				return args[0]
+			case *object.Float:
+				return &object.Integer{Value: int64(arg.Value)}
 			case *object.String:
 				if i, err := strconv.ParseInt(arg.Value, 10, 64); err == nil {
 					return &object.Integer{Value: int64(i)}
@@ -90,13 +112,58 @@ var builtins = map[string]*object.Builtin{
 			return newError("argument to `int` not supported, got %s", args[0].Type())
 		},
 	},
+	"float": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Float:
+				return args[0]
+			case *object.Integer:
+				return &object.Float{Value: float64(arg.Value)}
+			case *object.String:
+				if f, err := strconv.ParseFloat(arg.Value, 64); err == nil {
+					return &object.Float{Value: f}
+				}
+				return newError("could not convert type STRING to FLOAT")
+			case *object.Boolean:
+				if arg == TRUE {
+					return &object.Float{Value: 1}
+				}
+				return &object.Float{Value: 0}
+			}
+			return newError("argument to `float` not supported, got %s", args[0].Type())
+		},
+	},
+	"format_int": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) > 2 || len(args) < 1 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			if args[0].Type() != object.INTEGER_OBJ {
+				return newError("argument to `format_int` must be INTEGER, got %s", args[0].Type())
+			}
+
+			base := 10
+			if len(args) == 2 {
+				var err *object.Error
+				if base, err = baseArgument(args[1], false); err != nil {
+					return err
+				}
+			}
+
+			return &object.String{Value: strconv.FormatInt(args[0].(*object.Integer).Value, base)}
+		},
+	},
 	"type": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
 
-			return &object.String{Value: string(args[0].Type())}
+			return &object.String{Value: typeName(args[0])}
 		},
 	},
 	"input": {
@@ -113,38 +180,81 @@ var builtins = map[string]*object.Builtin{
 			return &object.String{Value: scanner.Text()}
 		},
 	},
-	"set": {
+	"append": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 3 {
-				return newError("wrong number of arguments. got=%d, want=3", len(args))
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
 				return newError("invalid array type. got=%s, want=ARRAY", args[0].Type())
 			}
-			arr := args[0].(*object.Array)
-			if args[1].Type() != object.INTEGER_OBJ {
-				return newError("invalid index type. got=%s. want=INTEGER", args[1].Type())
+			elements := args[0].(*object.Array).Elements
+			elements = append(elements, args[1])
+			args[0].(*object.Array).Elements = elements
+			return NONE
+		},
+	},
+	"keys": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != object.HASH_OBJ {
+				return newError("invalid hash type. got=%s, want=HASH", args[0].Type())
 			}
-			index := args[1].(*object.Integer)
-			if int(index.Value) >= len(arr.Elements) || index.Value < 0 {
-				return newError("index %d out of range", index.Value)
+			hash := args[0].(*object.Hash)
+			arr := &object.Array{Elements: []object.Object{}}
+			for _, key := range hash.Keys {
+				arr.Elements = append(arr.Elements, hash.Pairs[key].Key)
 			}
-			args[0].(*object.Array).Elements[int(index.Value)] = args[2]
-			return NONE
+			return arr
 		},
 	},
-	"append": {
+	"values": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != object.HASH_OBJ {
+				return newError("invalid hash type. got=%s, want=HASH", args[0].Type())
+			}
+			hash := args[0].(*object.Hash)
+			arr := &object.Array{Elements: []object.Object{}}
+			for _, key := range hash.Keys {
+				arr.Elements = append(arr.Elements, hash.Pairs[key].Value)
+			}
+			return arr
+		},
+	},
+	"has": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
 				return newError("wrong number of arguments. got=%d, want=2", len(args))
 			}
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("invalid array type. got=%s, want=ARRAY", args[0].Type())
+			if args[0].Type() != object.HASH_OBJ {
+				return newError("invalid hash type. got=%s, want=HASH", args[0].Type())
 			}
-			elements := args[0].(*object.Array).Elements
-			elements = append(elements, args[1])
-			args[0].(*object.Array).Elements = elements
-			return NONE
+			key, ok := args[1].(object.Hashable)
+			if !ok {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+			_, ok = args[0].(*object.Hash).Get(key)
+			return nativeBoolToBooleanObject(ok)
+		},
+	},
+	"delete": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			if args[0].Type() != object.HASH_OBJ {
+				return newError("invalid hash type. got=%s, want=HASH", args[0].Type())
+			}
+			key, ok := args[1].(object.Hashable)
+			if !ok {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+			return nativeBoolToBooleanObject(args[0].(*object.Hash).Delete(key))
 		},
 	},
 	"split": {
@@ -164,6 +274,7 @@ var builtins = map[string]*object.Builtin{
 				return newError("invalid string type. got=%s, want=STRING", args[0].Type())
 			}
 			str := args[0].(*object.String).Value
+			// an empty separator splits into characters, not bytes
 			tempElems := strings.Split(str, sep)
 			for _, elem := range tempElems {
 				arr.Elements = append(arr.Elements, &object.String{Value: elem})
@@ -171,4 +282,63 @@ var builtins = map[string]*object.Builtin{
 			return arr
 		},
 	},
+	"error": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) > 2 || len(args) < 1 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			// error(msg, kind) sets a kind other than Error
+			kind := "Error"
+			if len(args) == 2 {
+				if args[1].Type() != object.STRING_OBJ {
+					return newError("argument to `error` must be STRING, got %s", args[1].Type())
+				}
+				kind = args[1].(*object.String).Value
+			}
+
+			msg, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `error` must be STRING, got %s", args[0].Type())
+			}
+
+			return &object.ErrorValue{Err: &object.Error{Message: msg.Value, Kind: kind}}
+		},
+	},
+	"is_error": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			return nativeBoolToBooleanObject(args[0].Type() == object.ERROR_VALUE_OBJ)
+		},
+	},
+	"unwrap_or": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			if args[0].Type() == object.ERROR_VALUE_OBJ {
+				return args[1]
+			}
+			return args[0]
+		},
+	},
+}
+
+// baseArgument validates a numeric base argument. Bases 2 to 36
+// are accepted, and 0 as well when allowAuto is set
+func baseArgument(arg object.Object, allowAuto bool) (int, *object.Error) {
+	if arg.Type() != object.INTEGER_OBJ {
+		return 0, newError("invalid base type. got=%s, want=INTEGER", arg.Type())
+	}
+
+	base := arg.(*object.Integer).Value
+	if (base < 2 || base > 36) && !(allowAuto && base == 0) {
+		return 0, newError("invalid base %d", base)
+	}
+
+	return int(base), nil
 }
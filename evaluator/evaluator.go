@@ -1,20 +1,36 @@
 package evaluator
 
 import (
+	"bytes"
 	"fmt"
+	"math"
+	"strings"
 	"zlang/ast"
 	"zlang/object"
 )
 
 var (
-	NULL  = &object.Null{}
-	NONE  = &object.None{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	NONE     = &object.None{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 // Eval is the language evaluator
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+
+	// errors are tagged with the innermost node that produced them
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// Statements
@@ -29,43 +45,96 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		if isAbrupt(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
-		if isError(val) {
+		if isAbrupt(val) {
 			return val
 		}
+		if node.Pattern != nil {
+			if err := bindPattern(node.Pattern, val, env); err != nil {
+				return err
+			}
+			return NONE
+		}
 		env.Set(node.Name.Value, val)
+		return NONE
+
+	case *ast.FunctionStatement:
+		env.Set(node.Name.Value, newFunction(node.Function, env))
+		return NONE
+
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+
+	case *ast.StructStatement:
+		env.Set(node.Name.Value, newStructConstructor(node))
+		return NONE
+
+	case *ast.EnumStatement:
+		env.Set(node.Name.Value, newEnum(node))
+		return NONE
+
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+
+	case *ast.DeferStatement:
+		return evalDeferStatement(node, env)
+
+	case *ast.AssignStatement:
+		return evalAssignStatement(node, env)
+
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
 
 	// Expressions
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isAbrupt(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isAbrupt(left) {
 			return left
 		}
 
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isAbrupt(right) {
 			return right
 		}
 
@@ -74,21 +143,39 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.SwitchExpression:
+		return evalSwitchExpression(node, env)
+
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+
+	case *ast.PropagateExpression:
+		val := Eval(node.Value, env)
+		if isAbrupt(val) {
+			return val
+		}
+		// an error value returns from the enclosing function
+		if val.Type() == object.ERROR_VALUE_OBJ {
+			return &object.ReturnValue{Value: val}
+		}
+		return val
+
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 
 	case *ast.FunctionLiteral:
-		params := node.Parameters
-		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return newFunction(node, env)
 
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
-		if isError(function) {
+		if isAbrupt(function) {
 			return function
 		}
 		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
+		if len(args) == 1 && isAbrupt(args[0]) {
 			return args[0]
 		}
 
@@ -96,18 +183,31 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
+		if len(elements) == 1 && isAbrupt(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
 
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
+
+	case *ast.MemberExpression:
+		obj := Eval(node.Object, env)
+		if isAbrupt(obj) {
+			return obj
+		}
+		return evalMemberExpression(obj, node.Property.Value)
+
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
+
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isAbrupt(left) {
 			return left
 		}
 		index := Eval(node.Index, env)
-		if isError(index) {
+		if isAbrupt(index) {
 			return index
 		}
 		return evalIndexExpression(left, index)
@@ -119,6 +219,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
+	hoistFunctions(program.Statements, env)
+
 	for _, statement := range program.Statements {
 		result = Eval(statement, env)
 
@@ -136,12 +238,14 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
+	hoistFunctions(block.Statements, env)
+
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 
 		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
 				return result
 			}
 		}
@@ -150,6 +254,270 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+// hoistFunctions binds every function declared in statements
+// before any of them run, so declarations can call each other
+// regardless of their order
+func hoistFunctions(statements []ast.Statement, env *object.Environment) {
+	for _, statement := range statements {
+		if fs, ok := statement.(*ast.FunctionStatement); ok {
+			env.Set(fs.Name.Value, newFunction(fs.Function, env))
+		}
+	}
+}
+
+// newStructConstructor returns the builtin that creates
+// instances of the struct, taking the fields in order
+func newStructConstructor(ss *ast.StructStatement) *object.Builtin {
+	name := ss.Name.Value
+	fields := []string{}
+	for _, f := range ss.Fields {
+		fields = append(fields, f.Value)
+	}
+
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if len(args) != len(fields) {
+			return newError("wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), len(fields))
+		}
+
+		values := make(map[string]object.Object, len(fields))
+		for i, field := range fields {
+			values[field] = args[i]
+		}
+
+		return &object.Instance{Name: name, Fields: fields, Values: values}
+	}}
+}
+
+// newEnum builds the enum declared by es, with a shared value
+// for each variant without a payload and a constructor builtin
+// for each one with
+func newEnum(es *ast.EnumStatement) *object.Enum {
+	enum := &object.Enum{Name: es.Name.Value, Variants: map[string]object.Object{}}
+
+	for _, v := range es.Variants {
+		if v.Fields == nil {
+			enum.Variants[v.Name.Value] = &object.Variant{Enum: enum, Name: v.Name.Value}
+			continue
+		}
+
+		name := v.Name.Value
+		fields := []string{}
+		for _, f := range v.Fields {
+			fields = append(fields, f.Value)
+		}
+
+		enum.Variants[name] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != len(fields) {
+				return newError("wrong number of arguments to `%s.%s`. got=%d, want=%d", enum.Name, name, len(args), len(fields))
+			}
+			values := append([]object.Object{}, args...)
+			return &object.Variant{Enum: enum, Name: name, Fields: fields, Values: values}
+		}}
+	}
+
+	return enum
+}
+
+func newFunction(lit *ast.FunctionLiteral, env *object.Environment) *object.Function {
+	return &object.Function{
+		Name:       lit.Name,
+		Parameters: lit.Parameters,
+		Defaults:   lit.Defaults,
+		Rest:       lit.Rest,
+		Body:       lit.Body,
+		Env:        env,
+	}
+}
+
+func evalAssignStatement(as *ast.AssignStatement, env *object.Environment) object.Object {
+	switch target := as.Target.(type) {
+	case *ast.Identifier:
+		var current object.Object
+		if as.Operator != "=" {
+			current = Eval(target, env)
+			if isAbrupt(current) {
+				return current
+			}
+		}
+
+		val := evalAssignedValue(as, current, env)
+		if isAbrupt(val) {
+			return val
+		}
+
+		if !env.Assign(target.Value, val) {
+			return newError("assignment to undeclared identifier: %s", target.Value)
+		}
+
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		index := Eval(target.Index, env)
+		if isAbrupt(index) {
+			return index
+		}
+
+		var current object.Object
+		if as.Operator != "=" {
+			current = evalIndexExpression(left, index)
+			if isAbrupt(current) {
+				return current
+			}
+		}
+
+		val := evalAssignedValue(as, current, env)
+		if isAbrupt(val) {
+			return val
+		}
+
+		if err := evalIndexAssignment(left, index, val); err != nil {
+			return err
+		}
+
+	case *ast.MemberExpression:
+		obj := Eval(target.Object, env)
+		if isAbrupt(obj) {
+			return obj
+		}
+
+		var current object.Object
+		if as.Operator != "=" {
+			current = evalMemberExpression(obj, target.Property.Value)
+			if isAbrupt(current) {
+				return current
+			}
+		}
+
+		val := evalAssignedValue(as, current, env)
+		if isAbrupt(val) {
+			return val
+		}
+
+		setter, ok := obj.(object.FieldSetter)
+		if !ok {
+			return newError("field assignment not supported: %s", obj.Type())
+		}
+		if !setter.SetField(target.Property.Value, val) {
+			return newError("unknown field: %s.%s", typeName(obj), target.Property.Value)
+		}
+
+	default:
+		return newError("cannot assign to %s", as.Target.String())
+	}
+
+	return NONE
+}
+
+// evalAssignedValue evaluates the right hand side of an assignment.
+// For compound operators like += it is combined with current
+func evalAssignedValue(
+	as *ast.AssignStatement,
+	current object.Object,
+	env *object.Environment,
+) object.Object {
+	val := Eval(as.Value, env)
+	if isAbrupt(val) || as.Operator == "=" {
+		return val
+	}
+
+	operator := strings.TrimSuffix(as.Operator, "=")
+	return evalInfixExpression(operator, current, val)
+}
+
+func evalIndexAssignment(left, index, val object.Object) *object.Error {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		elements := left.(*object.Array).Elements
+		idx, ok := normalizeIndex(index.(*object.Integer).Value, len(elements))
+		if !ok {
+			return newError("index %d out of range", index.(*object.Integer).Value)
+		}
+		elements[idx] = val
+	case left.Type() == object.HASH_OBJ:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		left.(*object.Hash).Set(key, val)
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+
+	return nil
+}
+
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NONE
+		}
+
+		if result, done := evalLoopBody(ws.Body, env); done {
+			return result
+		}
+	}
+}
+
+// evalForStatement runs a for loop. The init statement is
+// evaluated in a new scope so loop variables do not leak
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	loopEnv := object.NewEnclosedEnvironment(env)
+
+	if fs.Init != nil {
+		if init := Eval(fs.Init, loopEnv); isAbrupt(init) {
+			return init
+		}
+	}
+
+	for {
+		if fs.Condition != nil {
+			condition := Eval(fs.Condition, loopEnv)
+			if isAbrupt(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return NONE
+			}
+		}
+
+		if result, done := evalLoopBody(fs.Body, loopEnv); done {
+			return result
+		}
+
+		if fs.Post != nil {
+			if post := Eval(fs.Post, loopEnv); isAbrupt(post) {
+				return post
+			}
+		}
+	}
+}
+
+// evalLoopBody runs one iteration of a loop body. done reports
+// whether the loop has to stop, and result is then what the
+// loop evaluates to: NONE for a break, or the return value or
+// error that has to keep propagating
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (result object.Object, done bool) {
+	result = Eval(body, env)
+	if result == nil {
+		return nil, false
+	}
+
+	switch result.Type() {
+	case object.BREAK_OBJ:
+		return NONE, true
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+		return result, true
+	}
+
+	return nil, false
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -159,10 +527,12 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 
 func evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
-	case "!":
+	case "!", "not":
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalTildePrefixOperatorExpression(right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
@@ -182,11 +552,22 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
+}
+
+func evalTildePrefixOperatorExpression(right object.Object) object.Object {
+	if right.Type() != object.INTEGER_OBJ {
+		return newError("unknown operator: ~%s", right.Type())
+	}
 	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return &object.Integer{Value: ^value}
 }
 
 func evalInfixExpression(
@@ -196,8 +577,12 @@ func evalInfixExpression(
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case left.Type() == object.VARIANT_OBJ && right.Type() == object.VARIANT_OBJ:
+		return evalVariantInfixExpression(operator, left, right)
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	case operator == "==":
@@ -209,6 +594,54 @@ func evalInfixExpression(
 	}
 }
 
+// evalVariantInfixExpression compares enum variants. They are
+// equal when they are the same variant of the same enum and
+// their payloads, if any, are equal
+func evalVariantInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
+	leftVal := left.(*object.Variant)
+	rightVal := right.(*object.Variant)
+
+	equal := leftVal.Enum == rightVal.Enum && leftVal.Name == rightVal.Name
+	for i := 0; equal && i < len(leftVal.Values); i++ {
+		equal = objectsEqual(leftVal.Values[i], rightVal.Values[i])
+	}
+
+	switch operator {
+	case "==":
+		return nativeBoolToBooleanObject(equal)
+	case "!=":
+		return nativeBoolToBooleanObject(!equal)
+	default:
+		return newError("unknown operator: %s %s %s", typeName(left), operator, typeName(right))
+	}
+}
+
+// evalLogicalExpression evaluates && and ||. The right operand
+// is only evaluated when the left one does not decide the result
+func evalLogicalExpression(ie *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(ie.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	if ie.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+	if ie.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
+
+	right := Eval(ie.Right, env)
+	if isAbrupt(right) {
+		return right
+	}
+
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
 func evalIntegerInfixExpression(
 	operator string,
 	left, right object.Object,
@@ -224,7 +657,87 @@ func evalIntegerInfixExpression(
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero")
+		}
+		return &object.Integer{Value: leftVal % rightVal}
+	case "**":
+		if rightVal < 0 {
+			return &object.Float{Value: math.Pow(float64(leftVal), float64(rightVal))}
+		}
+		return &object.Integer{Value: integerPower(leftVal, rightVal)}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<", ">>":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal)
+		}
+		if operator == "<<" {
+			return &object.Integer{Value: leftVal << uint64(rightVal)}
+		}
+		return &object.Integer{Value: leftVal >> uint64(rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// integerPower raises base to a non-negative exp by squaring,
+// wrapping around on overflow like the other integer operators
+func integerPower(base, exp int64) int64 {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+	return result
+}
+
+// evalFloatInfixExpression handles float operands and mixed
+// integer/float operands, which are promoted to float
+func evalFloatInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
+	leftVal := floatValue(left)
+	rightVal := floatValue(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -261,9 +774,26 @@ func evalStringInfixExpression(
 	}
 }
 
+func evalInterpolatedString(
+	node *ast.InterpolatedString,
+	env *object.Environment,
+) object.Object {
+	var out bytes.Buffer
+
+	for _, part := range node.Parts {
+		val := Eval(part, env)
+		if isAbrupt(val) {
+			return val
+		}
+		out.WriteString(val.Inspect())
+	}
+
+	return &object.String{Value: out.String()}
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
-	if isError(condition) {
+	if isAbrupt(condition) {
 		return condition
 	}
 
@@ -276,10 +806,220 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 }
 
+func evalSwitchExpression(se *ast.SwitchExpression, env *object.Environment) object.Object {
+	subject := Eval(se.Subject, env)
+	if isAbrupt(subject) {
+		return subject
+	}
+
+	for _, c := range se.Cases {
+		for _, value := range c.Values {
+			val := Eval(value, env)
+			if isAbrupt(val) {
+				return val
+			}
+
+			if objectsEqual(subject, val) {
+				return Eval(c.Body, env)
+			}
+		}
+	}
+
+	if se.Default != nil {
+		return Eval(se.Default, env)
+	}
+
+	return NULL
+}
+
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(me.Subject, env)
+	if isAbrupt(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+
+		if bindPattern(arm.Pattern, subject, armEnv) != nil {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isAbrupt(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return Eval(arm.Body, armEnv)
+	}
+
+	return newError("no match arm matched %s", subject.Inspect())
+}
+
+// evalThrowStatement raises its value as an error. Strings
+// become the message, struct and enum values name the kind,
+// and throwing an error value raises the error it holds
+func evalThrowStatement(ts *ast.ThrowStatement, env *object.Environment) object.Object {
+	val := Eval(ts.Value, env)
+	if isAbrupt(val) {
+		return val
+	}
+
+	switch val := val.(type) {
+	case *object.ErrorValue:
+		return val.Err
+	case *object.String:
+		return &object.Error{Message: val.Value, Kind: "Error", Value: val}
+	case *object.Instance, *object.Variant:
+		return &object.Error{Message: val.Inspect(), Kind: typeName(val), Value: val}
+	default:
+		return &object.Error{Message: val.Inspect(), Kind: "Error", Value: val}
+	}
+}
+
+// evalTryExpression evaluates the body, handing any error it
+// raises to the catch block as a value. The finally block
+// always runs afterwards, and only replaces the result when
+// it raises an error or leaves through return, break or continue
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(te.Body, env)
+
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		catchEnv := object.NewEnclosedEnvironment(env)
+		if te.Param != nil {
+			catchEnv.Set(te.Param.Value, &object.ErrorValue{Err: err})
+		}
+		result = Eval(te.Catch, catchEnv)
+	}
+
+	if te.Finally != nil {
+		finally := Eval(te.Finally, env)
+		if finally != nil {
+			switch finally.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+				return finally
+			}
+		}
+	}
+
+	return result
+}
+
+// bindPattern binds the names introduced by pattern in env,
+// or returns an error explaining why val does not match it
+func bindPattern(pattern ast.Expression, val object.Object, env *object.Environment) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			env.Set(pattern.Value, val)
+		}
+		return nil
+
+	case *ast.ArrayPattern:
+		array, ok := val.(*object.Array)
+		if !ok {
+			return newError("cannot destructure %s as ARRAY", val.Type())
+		}
+
+		want := len(pattern.Elements)
+		if pattern.Rest == nil && len(array.Elements) != want {
+			return newError("wrong number of values to destructure. got=%d, want=%d", len(array.Elements), want)
+		}
+		if len(array.Elements) < want {
+			return newError("wrong number of values to destructure. got=%d, want at least %d", len(array.Elements), want)
+		}
+
+		for i, element := range pattern.Elements {
+			if err := bindPattern(element, array.Elements[i], env); err != nil {
+				return err
+			}
+		}
+
+		if pattern.Rest != nil && pattern.Rest.Value != "_" {
+			rest := make([]object.Object, len(array.Elements)-want)
+			copy(rest, array.Elements[want:])
+			env.Set(pattern.Rest.Value, &object.Array{Elements: rest})
+		}
+		return nil
+
+	case *ast.HashPattern:
+		hash, ok := val.(*object.Hash)
+		if !ok {
+			return newError("cannot destructure %s as HASH", val.Type())
+		}
+
+		for _, key := range pattern.Keys {
+			value, ok := hash.Get(&object.String{Value: key.Value})
+			if !ok {
+				return newError("key not found in destructured hash: %s", key.Value)
+			}
+			env.Set(key.Value, value)
+		}
+		return nil
+
+	default:
+		literal := Eval(pattern, env)
+		if err, ok := literal.(*object.Error); ok {
+			return err
+		}
+		if !objectsEqual(val, literal) {
+			return newError("%s does not match pattern %s", val.Inspect(), pattern.String())
+		}
+		return nil
+	}
+}
+
+// objectsEqual reports whether left == right would be true.
+// Values of unrelated types are never equal rather than an error
+func objectsEqual(left, right object.Object) bool {
+	if left.Type() != right.Type() && !(isNumber(left) && isNumber(right)) {
+		return false
+	}
+	return evalInfixExpression("==", left, right) == TRUE
+}
+
+// evalMemberExpression looks name up among the fields of obj,
+// then in the method table for its type, so a hash key shadows
+// a method of the same name. Methods come back as builtins
+// bound to obj
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	if fielder, ok := obj.(object.Fielder); ok {
+		if value, ok := fielder.Field(name); ok {
+			return value
+		}
+	}
+
+	if m, ok := methods[obj.Type()][name]; ok {
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return m(obj, args...)
+		}}
+	}
+
+	return newError("unknown method or field: %s.%s", typeName(obj), name)
+}
+
+// typeName is the name type() reports for obj, which is the
+// declared name for user defined types
+func typeName(obj object.Object) string {
+	if named, ok := obj.(object.TypeNamer); ok {
+		return named.TypeName()
+	}
+	return string(obj.Type())
+}
+
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
@@ -287,16 +1027,176 @@ func evalIndexExpression(left, index object.Object) object.Object {
 
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
+	idx, ok := normalizeIndex(index.(*object.Integer).Value, len(arrayObject.Elements))
 
-	if idx < 0 || idx > max {
-		return newError("index %d out of range", idx)
+	if !ok {
+		return newError("index %d out of range", index.(*object.Integer).Value)
 	}
 
 	return arrayObject.Elements[idx]
 }
 
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+	idx, ok := normalizeIndex(index.(*object.Integer).Value, len(runes))
+
+	if !ok {
+		return newError("index %d out of range", index.(*object.Integer).Value)
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+// normalizeIndex turns a negative index, which counts from the
+// end, into a plain one and reports whether it is in range
+func normalizeIndex(idx int64, length int) (int64, bool) {
+	if idx < 0 {
+		idx += int64(length)
+	}
+	return idx, idx >= 0 && idx < int64(length)
+}
+
+func evalSliceExpression(se *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(se.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	var values [3]*int64
+	for i, exp := range []ast.Expression{se.Start, se.End, se.Step} {
+		if exp == nil {
+			continue
+		}
+		bound := Eval(exp, env)
+		if isAbrupt(bound) {
+			return bound
+		}
+		integer, ok := bound.(*object.Integer)
+		if !ok {
+			return newError("slice indices must be INTEGER, got %s", bound.Type())
+		}
+		values[i] = &integer.Value
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		indices, err := sliceIndices(len(left.Elements), values[0], values[1], values[2])
+		if err != nil {
+			return err
+		}
+		elements := make([]object.Object, len(indices))
+		for i, idx := range indices {
+			elements[i] = left.Elements[idx]
+		}
+		return &object.Array{Elements: elements}
+
+	case *object.String:
+		runes := []rune(left.Value)
+		indices, err := sliceIndices(len(runes), values[0], values[1], values[2])
+		if err != nil {
+			return err
+		}
+		sliced := make([]rune, len(indices))
+		for i, idx := range indices {
+			sliced[i] = runes[idx]
+		}
+		return &object.String{Value: string(sliced)}
+
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+}
+
+// sliceIndices returns the indices picked by [start:end:step]
+// from a sequence of the given length. Omitted bounds are nil,
+// negative ones count from the end and out of range ones are
+// clamped, the same way Python slices work
+func sliceIndices(length int, start, end, step *int64) ([]int, *object.Error) {
+	n := int64(length)
+
+	stride := int64(1)
+	if step != nil {
+		stride = *step
+	}
+	if stride == 0 {
+		return nil, newError("slice step cannot be zero")
+	}
+
+	// going backwards, -1 stands for "before the first element"
+	lower, upper := int64(0), n
+	if stride < 0 {
+		lower, upper = -1, n-1
+	}
+
+	clamp := func(bound *int64, def int64) int64 {
+		if bound == nil {
+			return def
+		}
+		idx := *bound
+		if idx < 0 {
+			idx += n
+			if idx < lower {
+				idx = lower
+			}
+		} else if idx > upper {
+			idx = upper
+		}
+		return idx
+	}
+
+	var from, to int64
+	if stride > 0 {
+		from, to = clamp(start, lower), clamp(end, upper)
+	} else {
+		from, to = clamp(start, upper), clamp(end, lower)
+	}
+
+	indices := []int{}
+	for i := from; (stride > 0 && i < to) || (stride < 0 && i > to); i += stride {
+		indices = append(indices, int(i))
+	}
+
+	return indices, nil
+}
+
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	if value, ok := hash.(*object.Hash).Get(key); ok {
+		return value
+	}
+
+	return NULL
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for i, keyNode := range node.Keys {
+		key := Eval(keyNode, env)
+		if isAbrupt(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := Eval(node.Values[i], env)
+		if isAbrupt(value) {
+			return value
+		}
+
+		hash.Set(hashKey, value)
+	}
+
+	return hash
+}
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
@@ -317,7 +1217,7 @@ func evalExpressions(
 
 	for _, e := range exps {
 		evaluated := Eval(e, env)
-		if isError(evaluated) {
+		if isAbrupt(evaluated) {
 			return []object.Object{evaluated}
 		}
 		result = append(result, evaluated)
@@ -339,6 +1239,22 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
+func isNumber(obj object.Object) bool {
+	t := obj.Type()
+	return t == object.INTEGER_OBJ || t == object.FLOAT_OBJ
+}
+
+// floatValue returns the value of an Integer or Float as a float64
+func floatValue(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.Float:
+		return obj.Value
+	}
+	return 0
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
@@ -346,6 +1262,17 @@ func isError(obj object.Object) bool {
 	return false
 }
 
+// isAbrupt reports whether obj ends evaluation of the expression
+// it turned up in. Besides errors, that is a return raised from
+// inside an expression, as the ? operator does, so it unwinds
+// to the enclosing function the same way
+func isAbrupt(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ERROR_OBJ || obj.Type() == object.RETURN_VALUE_OBJ
+	}
+	return false
+}
+
 func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
@@ -353,9 +1280,12 @@ func newError(format string, a ...interface{}) *object.Error {
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv, err := extendFunctionEnv(fn, args)
+		if err != nil {
+			return err
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		return runDeferred(extendedEnv, unwrapReturnValue(evaluated))
 
 	case *object.Builtin:
 		return fn.Fn(args...)
@@ -364,17 +1294,119 @@ func applyFunction(fn object.Object, args []object.Object) object.Object {
 	}
 }
 
+// evalDeferStatement records its expression to run when the
+// enclosing function call returns. For a call the function and
+// its arguments are evaluated right away, so a deferred call
+// sees the values they had at the defer
+func evalDeferStatement(ds *ast.DeferStatement, env *object.Environment) object.Object {
+	deferred := &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return Eval(ds.Value, env)
+	}}
+
+	if call, ok := ds.Value.(*ast.CallExpression); ok {
+		function := Eval(call.Function, env)
+		if isAbrupt(function) {
+			return function
+		}
+		args := evalExpressions(call.Arguments, env)
+		if len(args) == 1 && isAbrupt(args[0]) {
+			return args[0]
+		}
+
+		deferred = &object.Builtin{Fn: func(...object.Object) object.Object {
+			return applyFunction(function, args)
+		}}
+	}
+
+	if !env.Defer(deferred) {
+		return newError("defer outside of a function")
+	}
+	return NONE
+}
+
+// runDeferred calls the functions deferred during a function
+// call, last deferred first, and returns the result of the
+// call. An error from a deferred call replaces the result
+// unless the call already failed
+func runDeferred(env *object.Environment, result object.Object) object.Object {
+	deferred := env.Deferred()
+
+	for i := len(deferred) - 1; i >= 0; i-- {
+		val := applyFunction(deferred[i], nil)
+		if err, ok := val.(*object.Error); ok && !isAbrupt(result) {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// extendFunctionEnv binds args to the parameters of fn. Missing
+// arguments take their defaults, which are evaluated in the new
+// environment so they can refer to earlier parameters
 func extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
-) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+) (*object.Environment, *object.Error) {
+	if err := checkArity(fn, len(args)); err != nil {
+		return nil, err
+	}
+
+	env := object.NewFunctionEnvironment(fn.Env)
 
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if paramIdx < len(args) {
+			env.Set(param.Value, args[paramIdx])
+			continue
+		}
+
+		val := Eval(fn.Defaults[paramIdx], env)
+		if err, ok := val.(*object.Error); ok {
+			return nil, err
+		}
+		env.Set(param.Value, val)
+	}
+
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
 	}
 
-	return env
+	return env, nil
+}
+
+// checkArity returns an error unless fn accepts n arguments
+func checkArity(fn *object.Function, n int) *object.Error {
+	max := len(fn.Parameters)
+	min := max
+	for i := range fn.Parameters {
+		if i < len(fn.Defaults) && fn.Defaults[i] != nil {
+			min = i
+			break
+		}
+	}
+
+	msg := "wrong number of arguments"
+	if fn.Name != "" {
+		msg += " to `" + fn.Name + "`"
+	}
+
+	switch {
+	case fn.Rest != nil && n < min:
+		return newError("%s. got=%d, want at least %d", msg, n, min)
+	case fn.Rest != nil:
+		return nil
+	case n < min || n > max:
+		if min == max {
+			return newError("%s. got=%d, want=%d", msg, n, min)
+		}
+		return newError("%s. got=%d, want=%d to %d", msg, n, min, max)
+	}
+
+	return nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -1,6 +1,10 @@
 package evaluator
 
 import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
 	"zlang/lexer"
 	"zlang/object"
@@ -27,6 +31,21 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"5 ** 0", 1},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 10", 1024},
+		{"-16 >> 2", -4},
+		{"1 << 3 + 1", 16},
+		{"0xff & 0b1010 | 1", 11},
 	}
 
 	for _, tt := range tests {
@@ -35,6 +54,78 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5", 1.5},
+		{"-2.5", -2.5},
+		{"1e-3", 0.001},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2},
+		{"7 / 2.0", 3.5},
+		{"10 - 0.25", 9.75},
+		{"float(3) / 4", 0.75},
+		{`float("2.5")`, 2.5},
+		{"float(true)", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		inspect  string
+	}{
+		{"1.0", 1, "1.0"},
+		{"2.5", 2.5, "2.5"},
+		{"1 / 3.0", 1 / 3.0, "0.3333333333333333"},
+		{"1e21", 1e21, "1e+21"},
+		{"float(1000000)", 1000000, "1000000.0"},
+		{"1234567.5", 1234567.5, "1234567.5"},
+		{"-1234567.5", -1234567.5, "-1234567.5"},
+		{"0.0001", 0.0001, "0.0001"},
+		{"0.00001", 0.00001, "1e-05"},
+		{"0.0", 0, "0.0"},
+		{"float(10)", 10, "10.0"},
+		{"1.0 / 0", math.Inf(1), "+Inf"},
+		{"2 ** -1", 0.5, "0.5"},
+		{"7.5 % 2", 1.5, "1.5"},
+		{"2.0 ** 0.5", math.Sqrt2, "1.4142135623730951"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if !testFloatObject(t, evaluated, tt.expected) {
+			continue
+		}
+		if evaluated.Inspect() != tt.inspect {
+			t.Errorf("wrong Inspect. expected=%q, got=%q", tt.inspect, evaluated.Inspect())
+		}
+	}
+}
+
 func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -88,6 +179,11 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{`"hello" == "hi"`, false},
 		{`"hello" != "hello"`, false},
 		{`"hello" != "hi"`, true},
+		{"1.5 < 2", true},
+		{"2 <= 1.5", false},
+		{"1 == 1.0", true},
+		{"0.1 + 0.2 != 0.3", true},
+		{"2.5 >= 2.5", true},
 	}
 
 	for _, tt := range tests {
@@ -120,6 +216,9 @@ func TestBangOperator(t *testing.T) {
 		{"!!true", true},
 		{"!!false", false},
 		{"!!5", true},
+		{"not true", false},
+		{"not false", true},
+		{"not not 5", true},
 	}
 
 	for _, tt := range tests {
@@ -140,6 +239,9 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
 		{`
 		if (10 > 1) {
 			if (10 > 1) {
@@ -169,6 +271,217 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	return true
 }
 
+// pair and hash describe an expected hash, in insertion order
+type pair struct {
+	key   interface{}
+	value interface{}
+}
+
+type hash []pair
+
+// field and instance describe an expected struct instance,
+// with its fields in declaration order
+type field struct {
+	name  string
+	value interface{}
+}
+
+type instance struct {
+	name   string
+	fields []field
+}
+
+// variant is an expected enum value, named as Enum.Variant
+type variant struct {
+	name   string
+	values []interface{}
+}
+
+// errorValue is an expected error held as a value
+type errorValue struct {
+	kind    string
+	message string
+}
+
+// errorAt is an expected error along with the position it was
+// raised at, for tests where the file matters
+type errorAt struct {
+	pos     string
+	message string
+}
+
+// testObject checks obj against an expected value written the way
+// the table tests write them: a string is an error message, nil is
+// NULL and a slice is an array
+func testObject(t *testing.T, obj object.Object, expected interface{}) bool {
+	switch expected := expected.(type) {
+	case int:
+		return testIntegerObject(t, obj, int64(expected))
+	case float64:
+		return testFloatObject(t, obj, expected)
+	case bool:
+		return testBooleanObject(t, obj, expected)
+	case nil:
+		return testNullObject(t, obj)
+	case string:
+		return testErrorObject(t, obj, expected)
+	case *object.String:
+		return testStringObject(t, obj, expected.Value)
+	case *object.None:
+		if obj != NONE {
+			t.Errorf("object is not NONE. got=%T (%+v)", obj, obj)
+			return false
+		}
+		return true
+	case []interface{}:
+		return testArrayObject(t, obj, expected)
+	case hash:
+		return testHashObject(t, obj, expected)
+	case instance:
+		return testInstanceObject(t, obj, expected)
+	case variant:
+		return testVariantObject(t, obj, expected)
+	case errorValue:
+		return testErrorValueObject(t, obj, expected)
+	case errorAt:
+		if !testErrorObject(t, obj, expected.message) {
+			return false
+		}
+		if pos := obj.(*object.Error).Pos.String(); pos != expected.pos {
+			t.Errorf("error has wrong position. expected=%q, got=%q", expected.pos, pos)
+			return false
+		}
+		return true
+	default:
+		t.Fatalf("unsupported expected value %T (%+v)", expected, expected)
+		return false
+	}
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("object is not Error. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		return false
+	}
+	return true
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	str, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if str.Value != expected {
+		t.Errorf("object has wrong value. got=%q, want=%q", str.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testArrayObject(t *testing.T, obj object.Object, expected []interface{}) bool {
+	array, ok := obj.(*object.Array)
+	if !ok {
+		t.Errorf("object is not Array. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if len(array.Elements) != len(expected) {
+		t.Errorf("array has wrong number of elements. got=%d, want=%d", len(array.Elements), len(expected))
+		return false
+	}
+	for i, el := range expected {
+		if !testObject(t, array.Elements[i], el) {
+			return false
+		}
+	}
+	return true
+}
+
+func testInstanceObject(t *testing.T, obj object.Object, expected instance) bool {
+	inst, ok := obj.(*object.Instance)
+	if !ok {
+		t.Errorf("object is not Instance. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if inst.Name != expected.name {
+		t.Errorf("instance has wrong struct. want=%q, got=%q", expected.name, inst.Name)
+		return false
+	}
+	if len(inst.Fields) != len(expected.fields) {
+		t.Errorf("instance has wrong number of fields. got=%d, want=%d", len(inst.Fields), len(expected.fields))
+		return false
+	}
+	for i, f := range expected.fields {
+		if inst.Fields[i] != f.name {
+			t.Errorf("instance has wrong field %d. want=%q, got=%q", i, f.name, inst.Fields[i])
+			return false
+		}
+		if !testObject(t, inst.Values[f.name], f.value) {
+			return false
+		}
+	}
+	return true
+}
+
+func testVariantObject(t *testing.T, obj object.Object, expected variant) bool {
+	v, ok := obj.(*object.Variant)
+	if !ok {
+		t.Errorf("object is not Variant. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if v.TypeName() != expected.name {
+		t.Errorf("wrong variant. want=%q, got=%q", expected.name, v.TypeName())
+		return false
+	}
+	if len(v.Values) != len(expected.values) {
+		t.Errorf("variant has wrong number of values. got=%d, want=%d", len(v.Values), len(expected.values))
+		return false
+	}
+	for i, value := range expected.values {
+		if !testObject(t, v.Values[i], value) {
+			return false
+		}
+	}
+	return true
+}
+
+func testErrorValueObject(t *testing.T, obj object.Object, expected errorValue) bool {
+	ev, ok := obj.(*object.ErrorValue)
+	if !ok {
+		t.Errorf("object is not ErrorValue. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if ev.Kind() != expected.kind || ev.Err.Message != expected.message {
+		t.Errorf("wrong error value. want=%s: %q, got=%s: %q", expected.kind, expected.message, ev.Kind(), ev.Err.Message)
+		return false
+	}
+	return true
+}
+
+func testHashObject(t *testing.T, obj object.Object, expected hash) bool {
+	h, ok := obj.(*object.Hash)
+	if !ok {
+		t.Errorf("object is not Hash. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if len(h.Keys) != len(expected) {
+		t.Errorf("hash has wrong number of pairs. got=%d, want=%d", len(h.Keys), len(expected))
+		return false
+	}
+	for i, p := range expected {
+		got := h.Pairs[h.Keys[i]]
+		if !testObject(t, got.Key, p.key) || !testObject(t, got.Value, p.value) {
+			return false
+		}
+	}
+	return true
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -200,10 +513,34 @@ func TestErrorHandling(t *testing.T) {
 			"5 + true; 5",
 			"type mismatch: INTEGER + BOOLEAN",
 		},
+		{
+			"1.5 + true;",
+			"type mismatch: FLOAT + BOOLEAN",
+		},
 		{
 			"-true",
 			"unknown operator: -BOOLEAN",
 		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"let x = 0; 10 % x",
+			"modulo by zero",
+		},
+		{
+			"1 << -1",
+			"negative shift count: -1",
+		},
+		{
+			"~1.5",
+			"unknown operator: ~FLOAT",
+		},
+		{
+			"1.5 & 1",
+			"unknown operator: FLOAT & INTEGER",
+		},
 		{
 			"true + false;",
 			"unknown operator: BOOLEAN + BOOLEAN",
@@ -253,6 +590,40 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedInspect string
+	}{
+		{
+			"let x = 1;\nx + y;",
+			"bruh moment: 2:5: identifier not found: y",
+		},
+		{
+			"let f = fn() {\n  5 + true;\n};\nf();",
+			"bruh moment: 2:5: type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"len(1)",
+			"bruh moment: 1:4: argument to `len` not supported, got INTEGER",
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Inspect() != tt.expectedInspect {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedInspect, errObj.Inspect())
+		}
+	}
+}
+
 func TestLetStatement(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -311,6 +682,90 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(x, y = 10) { x + y }; f(1)", 11},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2)", 3},
+		{"let f = fn(x, y = x * 2) { x + y }; f(3)", 9},
+		{"let f = fn(x = 1, y = 2) { x * 10 + y }; f()", 12},
+		{"let n = 5; let f = fn(x = n) { x }; let n = 6; f()", 6},
+		{"let f = fn(first, ...rest) { rest }; f(1, 2, 3)", []interface{}{2, 3}},
+		{"let f = fn(first, ...rest) { rest }; f(1)", []interface{}{}},
+		{"let f = fn(...args) { len(args) }; f()", 0},
+		{"let f = fn(x, y = 2, ...rest) { x + y + len(rest) }; f(1, 1, 1, 1)", 4},
+		{"let f = fn(x) { x }; f()", "wrong number of arguments. got=0, want=1"},
+		{"let f = fn(x) { x }; f(1, 2)", "wrong number of arguments. got=2, want=1"},
+		{"let f = fn(x, y = 1) { x }; f()", "wrong number of arguments. got=0, want=1 to 2"},
+		{"let f = fn(x, ...rest) { x }; f()", "wrong number of arguments. got=0, want at least 1"},
+		{"let f = fn(x = y) { x }; f()", "identifier not found: y"},
+		{"let f = fn(x, y) { x }; f(1, 2)", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+
+	evaluated := testEval("fn(x, y = 1) { x }")
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(fn.Defaults) != 2 || fn.Defaults[0] != nil || fn.Defaults[1] == nil {
+		t.Errorf("wrong defaults. got=%v", fn.Defaults)
+	}
+	if fn.Inspect() != "fn(x, y = 1) {\nx\n}" {
+		t.Errorf("wrong Inspect. got=%q", fn.Inspect())
+	}
+}
+
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn double(x) { x * 2 }; double(4)", 8},
+		{"let r = double(4); fn double(x) { x * 2 }; r", 8},
+		{`
+		fn isEven(n) { if (n == 0) { true } else { isOdd(n - 1) } }
+		fn isOdd(n) { if (n == 0) { false } else { isEven(n - 1) } }
+		if (isEven(10)) { 1 } else { 0 }
+		`, 1},
+		{`
+		fn outer(n) {
+			return inner(n) + 1;
+			fn inner(m) { m * 10 }
+		}
+		outer(2)
+		`, 21},
+		{"fn fact(n) { if (n < 2) { return 1; } n * fact(n - 1) }; fact(5)", 120},
+		{"fn f(x) { x }; f()", "wrong number of arguments to `f`. got=0, want=1"},
+		{"fn f(x, ...rest) { x }; f()", "wrong number of arguments to `f`. got=0, want at least 1"},
+		{"if (true) { fn g() { 3 } }; g()", 3},
+		{"let f = fn() { fn inner() { 1 } }; [f()]", []interface{}{NONE}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+
+	evaluated := testEval("fn f(x) { x }; f")
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+	if fn.Name != "f" {
+		t.Errorf("function has wrong name. want=%q, got=%q", "f", fn.Name)
+	}
+	if fn.Inspect() != "fn f(x) {\nx\n}" {
+		t.Errorf("wrong Inspect. got=%q", fn.Inspect())
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := `
 	let newAdder = fn(x) {
@@ -352,6 +807,40 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let arr = [1, 2, 3]; "length is ${len(arr)}."`, "length is 3."},
+		{`let name = "bob"; "hi ${name}, ${name}!"`, "hi bob, bob!"},
+		{`"${1 + 1}${"x"}${[1, "a"]}${true}"`, `2x[1, "a"]true`},
+		{`let f = fn(x) { "<${x}>" }; "${f("${1.5}")}"`, "<1.5>"},
+		{`"price: \${x}"`, "price: ${x}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	evaluated := testEval(`"x is ${x}"`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: x" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -360,15 +849,33 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
+		{`len("日本語")`, 3},
+		{`len(split("日本語", ""))`, 3},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
-		{`int()`, "wrong number of arguments. got=0, want=1"},
+		{`int()`, "wrong number of arguments. got=0, want=1 or 2"},
+		{`int("ff", 16)`, 255},
+		{`int("0b101", 0)`, 5},
+		{`int("-777", 8)`, -511},
+		{`int("zz", 10)`, "could not convert \"zz\" to INTEGER in base 10"},
+		{`int("10", 1)`, "invalid base 1"},
+		{`int(10, 2)`, "argument to `int` with a base must be STRING, got INTEGER"},
+		{`format_int(255, 16)`, &object.String{Value: "ff"}},
+		{`format_int(5, 2)`, &object.String{Value: "101"}},
+		{`format_int(0x10)`, &object.String{Value: "16"}},
+		{`format_int(1, 0)`, "invalid base 0"},
+		{`format_int("1", 2)`, "argument to `format_int` must be INTEGER, got STRING"},
 		{`int("string")`, "could not convert type STRING to INTEGER"},
 		{`int([1, 2, 3, 4])`, "argument to `int` not supported, got ARRAY"},
 		{`int(true)`, 1},
 		{`int(false)`, 0},
 		{`int("69")`, 69},
 		{`int(69)`, 69},
+		{`int(3.9)`, 3},
+		{`float("abc")`, "could not convert type STRING to FLOAT"},
+		{`float([1])`, "argument to `float` not supported, got ARRAY"},
+		{`type(1.5)`, &object.String{Value: "FLOAT"}},
 		{`print(10)`, NONE},
 		{`type(10)`, &object.String{Value: "INTEGER"}},
 		{`type([1, 2, 3])`, &object.String{Value: "ARRAY"}},
@@ -377,6 +884,7 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`str(10)`, &object.String{Value: "10"}},
 		{`str([1, 2, 3, 4])`, &object.String{Value: "[1, 2, 3, 4]"}},
 		{`str(true)`, &object.String{Value: "true"}},
+		{`str(["a", "b\"c\n"])`, &object.String{Value: `["a", "b\"c\n"]`}},
 		{`str()`, "wrong number of arguments. got=0, want=1"},
 	}
 
@@ -478,7 +986,15 @@ func TestArrayIndexExpressions(t *testing.T) {
 		},
 		{
 			"[1, 2, 3][-1]",
-			"index -1 out of range",
+			3,
+		},
+		{
+			"[1, 2, 3][-3]",
+			1,
+		},
+		{
+			"[1, 2, 3][-4]",
+			"index -4 out of range",
 		},
 	}
 
@@ -499,3 +1015,812 @@ func TestArrayIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello"[0]`, "h"},
+		{`"héllo"[1]`, "é"},
+		{`"日本語"[2]`, "語"},
+		{`let s = "añb"; s[len(s) - 1]`, "b"},
+		{`"añb"[-2]`, "ñ"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	evaluated := testEval(`"日本語"[3]`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "index 3 out of range" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
+	}
+
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+
+	for i, tt := range expected {
+		if result.Keys[i] != tt.key.HashKey() {
+			t.Errorf("key %d out of order", i)
+		}
+
+		value, ok := result.Get(tt.key)
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+
+		testIntegerObject(t, value, tt.value)
+	}
+
+	if result.Inspect() != `{"one": 1, "two": 2, "three": 3, 4: 4, true: 5, false: 6}` {
+		t.Errorf("wrong Inspect. got=%q", result.Inspect())
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`{"name": "bob", 1: true}[1]`, true},
+		{`{"foo": 5}[[1]]`, "unusable as hash key: ARRAY"},
+		{`{[1]: 5}`, "unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`keys({"a": 1, 2: "b"})`, []interface{}{&object.String{Value: "a"}, 2}},
+		{`values({"a": 1, 2: "b"})`, []interface{}{1, &object.String{Value: "b"}}},
+		{`keys({})`, []interface{}{}},
+		{`has({"a": 1}, "a")`, true},
+		{`has({"a": 1}, "b")`, false},
+		{`let h = {"a": 1, "b": 2, "c": 3}; delete(h, "b"); h`, hash{
+			{&object.String{Value: "a"}, 1},
+			{&object.String{Value: "c"}, 3},
+		}},
+		{`let h = {"a": 1}; delete(h, "z")`, false},
+		{`let h = {"a": 1}; delete(h, "a"); len(h)`, 0},
+		{`len({"a": 1, "b": 2})`, 2},
+		{`type({})`, &object.String{Value: "HASH"}},
+		{`keys([1])`, "invalid hash type. got=ARRAY, want=HASH"},
+		{`has({}, [])`, "unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			"let i = 0; while (i < 5) { let i = i + 1; }; i",
+			5,
+		},
+		{
+			"let acc = []; for (let i = 0; i < 5; let i = i + 1) { append(acc, i); }; acc",
+			[]interface{}{0, 1, 2, 3, 4},
+		},
+		{
+			"let i = 0; while (i < 2) { i += 1 }; i",
+			2,
+		},
+		{
+			"for (;;) { break }; 1",
+			1,
+		},
+		{
+			"let acc = []; for (let i = 0; i < 10; let i = i + 1) { if (i == 3) { break; } append(acc, i); }; acc",
+			[]interface{}{0, 1, 2},
+		},
+		{
+			"let acc = []; for (let i = 0; i < 6; let i = i + 1) { if (i == 1) { continue; } if (i == 4) { continue } append(acc, i); }; acc",
+			[]interface{}{0, 2, 3, 5},
+		},
+		{
+			"let i = 0; while (true) { let i = i + 1; if (i > 100000) { break; } }; i",
+			100001,
+		},
+		{
+			"let acc = []; for (let i = 0; i < 2; let i = i + 1) { for (let j = 0; j < 3; let j = j + 1) { if (j == 2) { break; } append(acc, [i, j]); } }; acc",
+			[]interface{}{[]interface{}{0, 0}, []interface{}{0, 1}, []interface{}{1, 0}, []interface{}{1, 1}},
+		},
+		{
+			"let find = fn(arr, x) { for (let i = 0; i < len(arr); let i = i + 1) { if (arr[i] == x) { return i; } } return -1; }; [find([4, 5, 6], 6), find([4], 7)]",
+			[]interface{}{2, -1},
+		},
+		{
+			"for (let i = 0; i < 3; let i = i + 1) { }; type(i)",
+			"identifier not found: i",
+		},
+		{
+			"let n = 0; while (n < 3) { let n = n + 1; n + true; }",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"while (false) { 1 }",
+			NONE,
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x += 2; x", 3},
+		{"let x = 10; x -= 4; x *= 3; x /= 2; x", 9},
+		{`let s = "a"; s += "b"; s`, &object.String{Value: "ab"}},
+		{"let x = 1; x += 0.5; x", 1.5},
+		{"let x = 1; let f = fn() { x = 5; }; f(); x", 5},
+		{"let x = 1; let f = fn() { let x = 2; x = 3; x }; [f(), x]", []interface{}{3, 1}},
+		{"let counter = fn() { let c = 0; fn() { c += 1; c } }; let next = counter(); next(); next(); next()", 3},
+		{"let arr = [1, 2, 3]; arr[1] = 20; arr", []interface{}{1, 20, 3}},
+		{"let arr = [1, 2, 3]; arr[2] *= 5; arr", []interface{}{1, 2, 15}},
+		{`let h = {"a": 1}; h["b"] = 2; h["a"] += 10; h`, hash{
+			{&object.String{Value: "a"}, 11},
+			{&object.String{Value: "b"}, 2},
+		}},
+		{"let sum = 0; for (let i = 1; i <= 4; i += 1) { sum += i; }; sum", 10},
+		{"let i = 0; while (i < 3) { i += 1; }; i", 3},
+		{"y = 1;", "assignment to undeclared identifier: y"},
+		{"y += 1;", "identifier not found: y"},
+		{"let x = 1; x += true;", "type mismatch: INTEGER + BOOLEAN"},
+		{"let arr = [1]; arr[1] = 2;", "index 1 out of range"},
+		{`let s = "abc"; s[0] = "x";`, "index assignment not supported: STRING"},
+		{`let h = {}; h[[1]] = 1;`, "unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"true || false", true},
+		{"false || false", false},
+		{"false || true", true},
+		{"1 && 2", true},
+		{"if (false) { 1 } || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"not (1 > 2) && true", true},
+		{"false && undefinedName", false},
+		{"true || undefinedName", true},
+		{"let calls = []; let f = fn() { append(calls, 1); true }; false && f(); len(calls) == 0", true},
+		{"let calls = []; let f = fn() { append(calls, 1); true }; true && f(); len(calls) == 1", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+
+	evaluated := testEval("true && undefinedName")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: undefinedName" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestSwitchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"switch (1) { case 1 { 10 } case 2 { 20 } }", 10},
+		{"switch (2) { case 1 { 10 } case 2 { 20 } }", 20},
+		{"switch (3) { case 1 { 10 } case 2 { 20 } }", nil},
+		{"switch (3) { case 1, 2 { 10 } default { 30 } }", 30},
+		{"switch (2) { case 1, 2 { 10 } default { 30 } }", 10},
+		{"switch (2.0) { case 1 { 10 } case 2 { 20 } }", 20},
+		{`switch ("b") { case "a" { 1 } case "b" { 2 } }`, 2},
+		{`switch ("1") { case 1 { 1 } default { 2 } }`, 2},
+		{"let x = 5; switch (x * 2) { case x { 1 } case x + 5 { 2 } }", 2},
+		{"let f = fn(n) { switch (n) { case 0 { return 1 } default { 2 } } }; f(0)", 1},
+		{"let i = 0; while (true) { switch (i) { case 3 { break } default { i += 1 } } }; i", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"match 0 { 0 => 10, _ => 20 }", 10},
+		{"match 5 { 0 => 10, _ => 20 }", 20},
+		{"match 5 { n => n * 2 }", 10},
+		{"match -1 { -1 => 1, _ => 2 }", 1},
+		{"match 2.0 { 2 => 1, _ => 2 }", 1},
+		{`match "b" { "a" => 1, "b" => 2, _ => 3 }`, 2},
+		{"match [1, 2] { [a, b] => a + b, _ => 0 }", 3},
+		{"match [1, 2, 3] { [a, b] => a + b, _ => 0 }", 0},
+		{"match [1, [2, 3]] { [a, [b, c]] => a + b + c, _ => 0 }", 6},
+		{"match [1, 2] { [2, b] => b, [1, b] => b * 10, _ => 0 }", 20},
+		{"match [] { [] => 1, _ => 2 }", 1},
+		{"match 5 { [a] => a, _ => 2 }", 2},
+		{"match 5 { n if n > 10 => 1, n if n > 1 => 2, _ => 3 }", 2},
+		{"match [3, 4] { [a, b] if a > b => a, [a, b] => b, _ => 0 }", 4},
+		{"match 1 { 1 => { let x = 5; x * 2 } _ => 0 }", 10},
+		{"match 1 { 2 => 0 }", "no match arm matched 1"},
+		{"match [1] { [a, b] => 0 }", "no match arm matched [1]"},
+		{"match 1 { n if n + true => 0, _ => 1 }", "type mismatch: INTEGER + BOOLEAN"},
+		{"let a = 1; match 2 { a => a }; a", 1},
+		{"match [1, 2, 3] { [a, ...rest] => a + len(rest), _ => 0 }", 3},
+		{"match [] { [a, ...rest] => 1, [...rest] => 2, _ => 0 }", 2},
+		{`match {"name": "z", "age": 3} { {name, age} => age, _ => 0 }`, 3},
+		{`match {"name": "z"} { {name, age} => 1, {name} => 2, _ => 0 }`, 2},
+		{"match 1 { {name} => 1, _ => 2 }", 2},
+		{"let f = fn(x) { match x { 0 => { return 1 } _ => 2 }; 3 }; f(0)", 1},
+		{"let f = fn(x) { match x { 0 => { return 1 } _ => 2 }; 3 }; f(1)", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestDestructuringLet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a + b", 3},
+		{"let [a, b, ...rest] = [1, 2, 3, 4]; rest", []interface{}{3, 4}},
+		{"let [a, b, ...rest] = [1, 2]; rest", []interface{}{}},
+		{"let [...all] = [1, 2]; all", []interface{}{1, 2}},
+		{"let arr = [1, 2, 3]; let [first, ...rest] = arr; rest[0] = 9; arr", []interface{}{1, 2, 3}},
+		{"let [[a, b], c] = [[1, 2], 3]; a + b + c", 6},
+		{"let [_, b] = [1, 2]; b", 2},
+		{"let [1, b] = [1, 2]; b", 2},
+		{`let {name, age} = {"name": "z", "age": 3}; "${name} ${age}"`, &object.String{Value: "z 3"}},
+		{`let [{x}, [y]] = [{"x": 1}, [2]]; x + y`, 3},
+		{"let [a, b] = [1, 2, 3];", "wrong number of values to destructure. got=3, want=2"},
+		{"let [a, b] = [1];", "wrong number of values to destructure. got=1, want=2"},
+		{"let [a, b, ...c] = [1];", "wrong number of values to destructure. got=1, want at least 2"},
+		{"let [a, b] = 1;", "cannot destructure INTEGER as ARRAY"},
+		{`let {name} = [1];`, "cannot destructure ARRAY as HASH"},
+		{`let {name, age} = {"name": "z"};`, "key not found in destructured hash: age"},
+		{"let [1, b] = [2, 3];", "2 does not match pattern 1"},
+		{"let [a, b] = [1, x];", "identifier not found: x"},
+		{"let f = fn() { let [a] = [1] }; [f()]", []interface{}{NONE}},
+		{"let f = fn() { let a = 1 }; type(f())", &object.String{Value: "NULL"}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"héllo".len()`, 5},
+		{`"Hello".upper()`, &object.String{Value: "HELLO"}},
+		{`"Hello".lower()`, &object.String{Value: "hello"}},
+		{`"  hi  ".trim().upper()`, &object.String{Value: "HI"}},
+		{`"a,b,c".split(",")`, []interface{}{
+			&object.String{Value: "a"},
+			&object.String{Value: "b"},
+			&object.String{Value: "c"},
+		}},
+		{`"hello".contains("ell")`, true},
+		{`"hello".starts_with("he")`, true},
+		{`"hello".ends_with("he")`, false},
+		{`"a-b-c".replace("-", "+")`, &object.String{Value: "a+b+c"}},
+		{"[1, 2, 3].len()", 3},
+		{"let a = [1]; a.push(2); a", []interface{}{1, 2}},
+		{"let a = [1, 2]; let b = a.pop(); [a, b]", []interface{}{[]interface{}{1}, 2}},
+		{"[1, 2, 3].contains(2.0)", true},
+		{`[1, "a", true].join(", ")`, &object.String{Value: "1, a, true"}},
+		{"[1, 2, 3].reverse()", []interface{}{3, 2, 1}},
+		{`let h = {"a": 1, "b": 2}; h.keys()`, []interface{}{&object.String{Value: "a"}, &object.String{Value: "b"}}},
+		{`let h = {"a": 1, "b": 2}; h.values()`, []interface{}{1, 2}},
+		{`{"a": 1}.has("a")`, true},
+		{`let h = {"a": 1}; h.delete("a"); h.len()`, 0},
+		{`{"a": 1}.get("b", 5)`, 5},
+		{`let person = {"name": "z", "age": 3}; person.name`, &object.String{Value: "z"}},
+		{`let h = {"keys": 1}; h.keys`, 1},
+		{`let h = {"keys": 1}; keys(h)`, []interface{}{&object.String{Value: "keys"}}},
+		{`let h = {"keys": 1}; h.keys()`, "not a function: INTEGER"},
+		{`let upper = "abc".upper; upper()`, &object.String{Value: "ABC"}},
+		{`"abc".nope`, "unknown method or field: STRING.nope"},
+		{`{"a": 1}.b`, "unknown method or field: HASH.b"},
+		{"5.abs()", "unknown method or field: INTEGER.abs"},
+		{"[].pop()", "pop from empty array"},
+		{`"abc".upper(1)`, "wrong number of arguments. got=1, want=0"},
+		{`"abc".contains(1)`, "argument to `contains` must be STRING, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3, 4, 5][1:3]", []interface{}{2, 3}},
+		{"[1, 2, 3, 4, 5][:2]", []interface{}{1, 2}},
+		{"[1, 2, 3, 4, 5][3:]", []interface{}{4, 5}},
+		{"[1, 2, 3, 4, 5][:]", []interface{}{1, 2, 3, 4, 5}},
+		{"[1, 2, 3, 4, 5][::2]", []interface{}{1, 3, 5}},
+		{"[1, 2, 3, 4, 5][1::2]", []interface{}{2, 4}},
+		{"[1, 2, 3, 4, 5][::-1]", []interface{}{5, 4, 3, 2, 1}},
+		{"[1, 2, 3, 4, 5][3:0:-1]", []interface{}{4, 3, 2}},
+		{"[1, 2, 3, 4, 5][-2:]", []interface{}{4, 5}},
+		{"[1, 2, 3, 4, 5][:-2]", []interface{}{1, 2, 3}},
+		{"[1, 2, 3, 4, 5][-100:100]", []interface{}{1, 2, 3, 4, 5}},
+		{"[1, 2, 3, 4, 5][10:]", []interface{}{}},
+		{"[1, 2, 3, 4, 5][3:1]", []interface{}{}},
+		{"[1, 2, 3, 4, 5][100:-100:-2]", []interface{}{5, 3, 1}},
+		{"let a = [1, 2]; let b = a[:]; b[0] = 9; a", []interface{}{1, 2}},
+		{`"hello"[1:4]`, &object.String{Value: "ell"}},
+		{`"héllo"[:2]`, &object.String{Value: "hé"}},
+		{`"hello"[::-1]`, &object.String{Value: "olleh"}},
+		{`"hello"[-3:]`, &object.String{Value: "llo"}},
+		{`"hello"[7:9]`, &object.String{Value: ""}},
+		{"let a = [1, 2, 3]; a[-1] = 9; a", []interface{}{1, 2, 9}},
+		{"let a = [1, 2, 3]; a[-4] = 9;", "index -4 out of range"},
+		{"[1, 2, 3][::0]", "slice step cannot be zero"},
+		{`[1, 2, 3]["a":]`, "slice indices must be INTEGER, got STRING"},
+		{"5[1:2]", "slice operator not supported: INTEGER"},
+		{`{"a": 1}[1:2]`, "slice operator not supported: HASH"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestImports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zlang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	zpath, err := ioutil.TempDir("", "zpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(zpath)
+
+	files := map[string]string{
+		filepath.Join(dir, "lib", "strings.z"): `
+			let greeting = "hello";
+			fn shout(s) { _exclaim(s.upper()) }
+			fn _exclaim(s) { s + "!" }
+			import "counter.z" as counter;
+			let count = counter.count;
+		`,
+		filepath.Join(dir, "lib", "counter.z"): `
+			let count = [0];
+			count[0] += 1;
+		`,
+		filepath.Join(dir, "a.z"):      `import "b.z" as b;`,
+		filepath.Join(dir, "b.z"):      `import "a.z" as a;`,
+		filepath.Join(dir, "entry.z"):  `import "back.z" as back;`,
+		filepath.Join(dir, "back.z"):   `import "entry.z" as entry;`,
+		filepath.Join(dir, "broken.z"): `let x = ;`,
+		filepath.Join(dir, "fails.z"):  `let x = 1 + true;`,
+		filepath.Join(zpath, "util.z"): `let answer = 42;`,
+	}
+	for path, source := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	os.Setenv("ZPATH", zpath)
+	defer os.Unsetenv("ZPATH")
+
+	main := filepath.Join(dir, "main.z")
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "lib/strings.z" as strs; strs.greeting`, &object.String{Value: "hello"}},
+		{`import "lib/strings.z" as strs; strs.shout("hi")`, &object.String{Value: "HI!"}},
+		{`import "lib/strings.z"; strings.greeting`, &object.String{Value: "hello"}},
+		{`import "lib/strings.z" as strs; type(strs)`, &object.String{Value: "MODULE"}},
+		{`import "lib/strings.z" as strs; strs._exclaim`, errorAt{main + ":1:37", "unknown method or field: MODULE._exclaim"}},
+		{`import "lib/strings.z" as s; import "lib/counter.z" as c; s.count[0]`, 1},
+		{`import "util.z" as util; util.answer`, 42},
+		{`let f = fn() { import "util.z" as util }; [f()]`, []interface{}{NONE}},
+		{`let f = fn() { import "util.z" as util }; type(f())`, &object.String{Value: "NULL"}},
+		{`import "missing.z" as m;`, errorAt{main + ":1:1", "module not found: missing.z"}},
+		{`import "a.z" as a;`, errorAt{filepath.Join(dir, "b.z") + ":1:1", "import cycle: a.z -> b.z -> a.z"}},
+		{`import "broken.z" as b;`, errorAt{main + ":1:1", "could not parse module " +
+			filepath.Join(dir, "broken.z") + ": " + filepath.Join(dir, "broken.z") + ":1:9: no prefix parse function for ; found"}},
+		{`import "fails.z" as f;`, errorAt{filepath.Join(dir, "fails.z") + ":1:11", "type mismatch: INTEGER + BOOLEAN"}},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEvalFile(t, tt.input, main), tt.expected)
+	}
+
+	evaluated := testEvalFile(t, `import "lib/strings.z" as strs; strs`, main)
+	module, ok := evaluated.(*object.Module)
+	if !ok {
+		t.Fatalf("object is not Module. got=%T (%+v)", evaluated, evaluated)
+	}
+	if module.Name != "strings" || module.Path != filepath.Join(dir, "lib", "strings.z") {
+		t.Errorf("wrong module. got name=%q, path=%q", module.Name, module.Path)
+	}
+
+	// the file being run counts as loading, so importing it back
+	// is a cycle rather than a second copy of it
+	entry := filepath.Join(dir, "entry.z")
+	program := parser.New(lexer.NewWithFile(files[entry], entry)).ParseProgram()
+	testObject(t, EvalFile(program, entry, object.NewEnvironment()),
+		errorAt{filepath.Join(dir, "back.z") + ":1:1", "import cycle: entry.z -> back.z -> entry.z"})
+}
+
+// testEvalFile evaluates input as if it were read from the file
+// at path, so imports resolve relative to it
+func testEvalFile(t *testing.T, input, path string) object.Object {
+	l := lexer.NewWithFile(input, path)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	return Eval(program, object.NewEnvironment())
+}
+
+func TestStructs(t *testing.T) {
+	point := func(x, y int) instance {
+		return instance{"Point", []field{{"x", x}, {"y", y}}}
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"struct Point { x, y }; Point(1, 2)", point(1, 2)},
+		{`struct Person { name, age }; Person("z", 3)`, instance{"Person", []field{
+			{"name", &object.String{Value: "z"}},
+			{"age", 3},
+		}}},
+		{"struct Empty {}; Empty()", instance{"Empty", nil}},
+		{"struct Point { x, y }; let p = Point(1, 2); p.x + p.y", 3},
+		{"struct Point { x, y }; let p = Point(1, 2); p.x = 5; p", point(5, 2)},
+		{"struct Point { x, y }; let p = Point(1, 2); p.y *= 10; p.y", 20},
+		{"struct Point { x, y }; type(Point(1, 2))", &object.String{Value: "Point"}},
+		{"struct Point { x, y }; type(Point)", &object.String{Value: "BUILTIN"}},
+		{"struct Line { a, b }; struct Point { x, y }; let l = Line(Point(0, 0), Point(1, 1)); l.b.x = 7; l",
+			instance{"Line", []field{{"a", point(0, 0)}, {"b", point(7, 1)}}}},
+		{"struct Point { x, y }; let p = Point(1, 2); p == p", true},
+		{"struct Point { x, y }; Point(1, 2) == Point(1, 2)", false},
+		{"struct Point { x, y }; [Point(1, 2)]", []interface{}{point(1, 2)}},
+		{"let f = fn() { struct P { x } }; [f()]", []interface{}{NONE}},
+		{`let h = {"a": 1}; h.a = 2; h.b = 3; h`, hash{
+			{&object.String{Value: "a"}, 2},
+			{&object.String{Value: "b"}, 3},
+		}},
+		{"struct Point { x, y }; Point(1)", "wrong number of arguments to `Point`. got=1, want=2"},
+		{"struct Point { x, y }; Point(1, 2).z", "unknown method or field: Point.z"},
+		{"struct Point { x, y }; let p = Point(1, 2); p.z = 1;", "unknown field: Point.z"},
+		{"let a = [1]; a.x = 1;", "field assignment not supported: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+
+	evaluated := testEval(`struct Person { name, age }; Person("z", 3)`)
+	if evaluated.Inspect() != `Person{name: "z", age: 3}` {
+		t.Errorf("wrong Inspect. got=%q", evaluated.Inspect())
+	}
+}
+
+func TestEnums(t *testing.T) {
+	shape := "enum Shape { Circle(r), Rect(w, h), Empty }; "
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{shape + "Shape.Empty", variant{"Shape.Empty", nil}},
+		{shape + "Shape.Circle(2)", variant{"Shape.Circle", []interface{}{2}}},
+		{shape + `Shape.Rect(2, "3")`, variant{"Shape.Rect", []interface{}{2, &object.String{Value: "3"}}}},
+		{shape + "Shape.Rect(2, 3).h", 3},
+		{shape + "type(Shape.Circle(1))", &object.String{Value: "Shape.Circle"}},
+		{shape + "type(Shape.Empty)", &object.String{Value: "Shape.Empty"}},
+		{shape + "type(Shape)", &object.String{Value: "ENUM"}},
+		{shape + "Shape.Empty == Shape.Empty", true},
+		{shape + "Shape.Empty != Shape.Empty", false},
+		{shape + "let a = Shape.Empty; let b = Shape.Empty; a == b", true},
+		{shape + "Shape.Empty == Shape.Circle(1)", false},
+		{shape + "Shape.Circle(1) == Shape.Circle(1)", true},
+		{shape + "Shape.Circle(1) == Shape.Circle(2)", false},
+		{shape + "enum Other { Empty }; Shape.Empty == Other.Empty", false},
+		{"let f = fn() { enum E { A } }; [f()]", []interface{}{NONE}},
+		{shape + "[Shape.Empty, Shape.Circle(1)]", []interface{}{
+			variant{"Shape.Empty", nil},
+			variant{"Shape.Circle", []interface{}{1}},
+		}},
+		{shape + `let area = fn(s) { switch (s) { case Shape.Empty { 0 } default { s.w * s.h } } }; area(Shape.Empty)`, 0},
+		{shape + "Shape.Empty == 1", "type mismatch: VARIANT == INTEGER"},
+		{shape + "Shape.Empty < Shape.Empty", "unknown operator: Shape.Empty < Shape.Empty"},
+		{shape + "Shape.Circle()", "wrong number of arguments to `Shape.Circle`. got=0, want=1"},
+		{shape + "Shape.Square", "unknown method or field: ENUM.Square"},
+		{shape + "Shape.Circle(1).w", "unknown method or field: Shape.Circle.w"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+
+	evaluated := testEval(shape + "Shape")
+	enum, ok := evaluated.(*object.Enum)
+	if !ok {
+		t.Fatalf("object is not Enum. got=%T (%+v)", evaluated, evaluated)
+	}
+	if enum.Name != "Shape" || len(enum.Variants) != 3 {
+		t.Errorf("wrong enum. got name=%q with %d variants", enum.Name, len(enum.Variants))
+	}
+
+	evaluated = testEval(shape + `Shape.Rect(2, "3")`)
+	if evaluated.Inspect() != `Shape.Rect(2, "3")` {
+		t.Errorf("wrong Inspect. got=%q", evaluated.Inspect())
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`throw "boom"`, errorAt{"1:1", "boom"}},
+		{`let f = fn() { throw "boom"; 1 }; f(); 2`, errorAt{"1:16", "boom"}},
+		{"try { 1 } catch (e) { 2 }", 1},
+		{`try { throw "boom" } catch (e) { e.message }`, &object.String{Value: "boom"}},
+		{`try { throw "boom" } catch (e) { e.kind }`, &object.String{Value: "Error"}},
+		{"try { 1 / 0 } catch (e) { e.message }", &object.String{Value: "division by zero"}},
+		{"try { 1 / 0 } catch (e) { e.kind }", &object.String{Value: "RuntimeError"}},
+		{"try { 1 / 0 } catch (e) { e }", errorValue{"RuntimeError", "division by zero"}},
+		{"try { 1 / 0 } catch (e) { type(e) }", &object.String{Value: "ERROR"}},
+		{"try { 1 / 0 } catch { 0 }", 0},
+		{"try { throw 42 } catch (e) { e.value + 1 }", 43},
+		{"struct NotFound { path }; try { throw NotFound(\"a.z\") } catch (e) { e.kind }", &object.String{Value: "NotFound"}},
+		{"struct NotFound { path }; try { throw NotFound(\"a.z\") } catch (e) { e.value.path }", &object.String{Value: "a.z"}},
+		{`let f = fn() { throw "inner" }; try { f() } catch (e) { "caught " + e.message }`, &object.String{Value: "caught inner"}},
+		{`try { try { throw "a" } catch (e) { throw e } } catch (e) { e.message }`, &object.String{Value: "a"}},
+		{`try { throw "a" } catch (e) { throw e }`, errorAt{"1:7", "a"}},
+		{`try { throw "a" } catch (e) { throw "b" }`, errorAt{"1:31", "b"}},
+		{"let x = 0; try { x = 1 } finally { x = 2 }; x", 2},
+		{"let x = 0; try { 1 / 0 } catch (e) { x = 1 } finally { x = x + 10 }; x", 11},
+		{"let x = 0; try { 1 / 0 } finally { x = 1 }", "division by zero"},
+		{"let x = 0; let f = fn() { try { return 1 } finally { x = 5 } }; f() + x", 6},
+		{"let f = fn() { try { 1 } finally { return 2 } }; f()", 2},
+		{"try { 1 / 0 } catch (e) { 0 }; e", "identifier not found: e"},
+		{"try { 1 / 0 } catch (e) { e.line }", "unknown method or field: ERROR.line"},
+		{"try { 1 / 0 } catch (e) { e.value }", "unknown method or field: ERROR.value"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+
+	evaluated := testEval("try { 1 / 0 } catch (e) { e }")
+	if evaluated.Inspect() != "RuntimeError: division by zero" {
+		t.Errorf("wrong Inspect. got=%q", evaluated.Inspect())
+	}
+}
+
+func TestErrorValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`error("boom")`, errorValue{"Error", "boom"}},
+		{`error("no such file", "NotFound").kind`, &object.String{Value: "NotFound"}},
+		{`error("boom").message`, &object.String{Value: "boom"}},
+		{`type(error("boom"))`, &object.String{Value: "ERROR"}},
+		{`is_error(error("boom"))`, true},
+		{"is_error(1)", false},
+		{"try { 1 / 0 } catch (e) { is_error(e) }", true},
+		{`unwrap_or(error("boom"), 5)`, 5},
+		{"unwrap_or(3, 5)", 3},
+		{`let x = error("boom"); 1`, 1},
+		{`throw error("boom")`, errorAt{"1:1", "boom"}},
+		{`try { throw error("gone", "NotFound") } catch (e) { e.kind }`, &object.String{Value: "NotFound"}},
+		{"error(1)", "argument to `error` must be STRING, got INTEGER"},
+		{"error()", "wrong number of arguments. got=0, want=1 or 2"},
+		{"unwrap_or(1)", "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestPropagateExpressions(t *testing.T) {
+	parse := `let parse = fn(s) { if (s == "") { return error("empty") } int(s) }; `
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{parse + `let f = fn(s) { parse(s)? + 1 }; f("41")`, 42},
+		{parse + `let f = fn(s) { parse(s)? + 1 }; f("")`, errorValue{"Error", "empty"}},
+		{parse + `let f = fn(s) { let n = parse(s)?; n + 1 }; is_error(f(""))`, true},
+		{parse + `let seen = []; let f = fn(s) { let n = parse(s)?; seen.push(n); n }; f(""); len(seen)`, 0},
+		{parse + `let f = fn(a) { for (let i = 0; i < len(a); i += 1) { parse(a[i])?; } "ok" }; f(["1", "", "2"])`, errorValue{"Error", "empty"}},
+		{parse + `let f = fn(a) { for (let i = 0; i < len(a); i += 1) { parse(a[i])?; } "ok" }; f(["1", "2"])`, &object.String{Value: "ok"}},
+		{parse + `let f = fn(s) { parse(s)? }; let g = fn(s) { f(s)?; "unreachable" }; g("")`, errorValue{"Error", "empty"}},
+		{parse + `let f = fn(s) { unwrap_or(parse(s), 0) + 1 }; f("")`, 1},
+		{"let f = fn() { 5? }; f()", 5},
+		{`let f = fn() { error("top")?; 1 }; f()`, errorValue{"Error", "top"}},
+		{"let f = fn(c) { let x = if (c) { return 1 } else { 2 }; x + 10 }; f(true)", 1},
+		{`let f = fn() { (1 / 0)?; 2 }; f()`, "division by zero"},
+		{`let log = []; let f = fn() { defer log.push(error("x")?); 1 }; [f(), log]`, []interface{}{errorValue{"Error", "x"}, []interface{}{}}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestDeferStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let log = []; let f = fn() { defer log.push(1); defer log.push(2); log.push(0) }; f(); log", []interface{}{0, 2, 1}},
+		{"let log = []; let f = fn() { defer log.push(1); return 5 }; [f(), log]", []interface{}{5, []interface{}{1}}},
+		{"let f = fn() { defer 1 }; [f()]", []interface{}{NONE}},
+		{"let f = fn() { defer 1 }; type(f())", &object.String{Value: "NULL"}},
+		{"let f = fn() { defer 1 }; 1 + f()", "type mismatch: INTEGER + NULL"},
+		{"let log = []; let f = fn() { defer log.push(1); 1 / 0 }; f()", "division by zero"},
+		{"let log = []; let f = fn() { defer log.push(1); 1 / 0 }; try { f() } catch { 0 }; log", []interface{}{1}},
+		{"let log = []; let f = fn() { defer log.push(1); error(\"x\")?; 2 }; [f(), log]", []interface{}{errorValue{"Error", "x"}, []interface{}{1}}},
+		{"let log = []; let f = fn() { for (let i = 0; i < 3; i += 1) { defer log.push(i) } }; f(); log", []interface{}{2, 1, 0}},
+		{"let log = []; let f = fn() { defer log.push(\"f\") }; let g = fn() { defer log.push(\"g\"); f(); log.push(\"body\") }; g(); log", []interface{}{
+			&object.String{Value: "f"},
+			&object.String{Value: "body"},
+			&object.String{Value: "g"},
+		}},
+		{"let f = fn() { let x = 1; defer x = 2; x }; f()", 1},
+		{"let f = fn() { defer 1 / 0; 5 }; f()", "division by zero"},
+		{"let f = fn() { defer undefined; 1 / 0 }; f()", "division by zero"},
+		{"let log = []; let f = fn() { defer 1 / 0; defer log.push(1) }; let r = try { f() } catch (e) { e.message }; [r, log]", []interface{}{
+			&object.String{Value: "division by zero"},
+			[]interface{}{1},
+		}},
+		{"let f = fn() { defer print(undefined) }; f()", "identifier not found: undefined"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
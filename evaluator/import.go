@@ -0,0 +1,124 @@
+package evaluator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"zlang/ast"
+	"zlang/lexer"
+	"zlang/object"
+	"zlang/parser"
+)
+
+func evalImportStatement(is *ast.ImportStatement, env *object.Environment) object.Object {
+	path, err := resolveImport(is.Path.Value, is.Pos().File)
+	if err != nil {
+		return err
+	}
+
+	module := loadModule(path, env)
+	if isError(module) {
+		return module
+	}
+
+	env.Set(is.Name.Value, module)
+	return NONE
+}
+
+// EvalFile evaluates program as the file at path the interpreter
+// was started with. The file counts as being loaded while it
+// runs, so a module that imports it back is an import cycle
+func EvalFile(program *ast.Program, path string, env *object.Environment) object.Object {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	cache := env.Modules()
+	cache.Loading = append(cache.Loading, path)
+	defer func() { cache.Loading = cache.Loading[:len(cache.Loading)-1] }()
+
+	return Eval(program, env)
+}
+
+// resolveImport finds the file an import refers to. Relative
+// paths are tried against the directory of the importing file
+// first, then against each directory listed in ZPATH
+func resolveImport(name, importer string) (string, *object.Error) {
+	if filepath.IsAbs(name) {
+		if fileExists(name) {
+			return name, nil
+		}
+		return "", newError("module not found: %s", name)
+	}
+
+	dirs := []string{filepath.Dir(importer)}
+	for _, dir := range filepath.SplitList(os.Getenv("ZPATH")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, name)
+		if fileExists(candidate) {
+			if abs, err := filepath.Abs(candidate); err == nil {
+				return abs, nil
+			}
+			return candidate, nil
+		}
+	}
+
+	return "", newError("module not found: %s", name)
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// loadModule evaluates the file at path in a fresh top level
+// environment, once per interpreter. Importing a module that
+// is still being loaded is an import cycle
+func loadModule(path string, env *object.Environment) object.Object {
+	cache := env.Modules()
+
+	if module, ok := cache.Loaded[path]; ok {
+		return module
+	}
+
+	for i, loading := range cache.Loading {
+		if loading == path {
+			cycle := []string{}
+			for _, p := range append(cache.Loading[i:], path) {
+				cycle = append(cycle, filepath.Base(p))
+			}
+			return newError("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return newError("could not read module %s: %s", path, err)
+	}
+
+	l := lexer.NewWithFile(string(source), path)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) != 0 {
+		return newError("could not parse module %s: %s", path, errors[0])
+	}
+
+	cache.Loading = append(cache.Loading, path)
+	defer func() { cache.Loading = cache.Loading[:len(cache.Loading)-1] }()
+
+	moduleEnv := env.NewModuleEnvironment()
+	if result := Eval(program, moduleEnv); isError(result) {
+		return result
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	module := &object.Module{Name: name, Path: path, Env: moduleEnv}
+	cache.Loaded[path] = module
+	return module
+}
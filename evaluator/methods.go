@@ -0,0 +1,177 @@
+package evaluator
+
+import (
+	"strings"
+	"zlang/object"
+)
+
+// method is a builtin that operates on the object it is
+// called on, as in receiver.name(args)
+type method func(receiver object.Object, args ...object.Object) object.Object
+
+// methods holds the method table of each type that has one
+var methods = map[object.ObjectType]map[string]method{
+	object.STRING_OBJ: stringMethods,
+	object.ARRAY_OBJ:  arrayMethods,
+	object.HASH_OBJ:   hashMethods,
+}
+
+var stringMethods = map[string]method{
+	"len": func(receiver object.Object, args ...object.Object) object.Object {
+		return builtins["len"].Fn(append([]object.Object{receiver}, args...)...)
+	},
+	"split": func(receiver object.Object, args ...object.Object) object.Object {
+		return builtins["split"].Fn(append([]object.Object{receiver}, args...)...)
+	},
+	"upper": func(receiver object.Object, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError("wrong number of arguments. got=%d, want=0", len(args))
+		}
+		return &object.String{Value: strings.ToUpper(receiver.(*object.String).Value)}
+	},
+	"lower": func(receiver object.Object, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError("wrong number of arguments. got=%d, want=0", len(args))
+		}
+		return &object.String{Value: strings.ToLower(receiver.(*object.String).Value)}
+	},
+	"trim": func(receiver object.Object, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError("wrong number of arguments. got=%d, want=0", len(args))
+		}
+		return &object.String{Value: strings.TrimSpace(receiver.(*object.String).Value)}
+	},
+	"contains": func(receiver object.Object, args ...object.Object) object.Object {
+		sub, err := stringArgument("contains", args)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(strings.Contains(receiver.(*object.String).Value, sub))
+	},
+	"starts_with": func(receiver object.Object, args ...object.Object) object.Object {
+		prefix, err := stringArgument("starts_with", args)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(strings.HasPrefix(receiver.(*object.String).Value, prefix))
+	},
+	"ends_with": func(receiver object.Object, args ...object.Object) object.Object {
+		suffix, err := stringArgument("ends_with", args)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(strings.HasSuffix(receiver.(*object.String).Value, suffix))
+	},
+	"replace": func(receiver object.Object, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError("wrong number of arguments. got=%d, want=2", len(args))
+		}
+		old, ok := args[0].(*object.String)
+		if !ok {
+			return newError("argument to `replace` must be STRING, got %s", args[0].Type())
+		}
+		replacement, ok := args[1].(*object.String)
+		if !ok {
+			return newError("argument to `replace` must be STRING, got %s", args[1].Type())
+		}
+		return &object.String{Value: strings.ReplaceAll(receiver.(*object.String).Value, old.Value, replacement.Value)}
+	},
+}
+
+var arrayMethods = map[string]method{
+	"len": func(receiver object.Object, args ...object.Object) object.Object {
+		return builtins["len"].Fn(append([]object.Object{receiver}, args...)...)
+	},
+	"push": func(receiver object.Object, args ...object.Object) object.Object {
+		return builtins["append"].Fn(append([]object.Object{receiver}, args...)...)
+	},
+	"pop": func(receiver object.Object, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError("wrong number of arguments. got=%d, want=0", len(args))
+		}
+		array := receiver.(*object.Array)
+		if len(array.Elements) == 0 {
+			return newError("pop from empty array")
+		}
+		last := array.Elements[len(array.Elements)-1]
+		array.Elements = array.Elements[:len(array.Elements)-1]
+		return last
+	},
+	"contains": func(receiver object.Object, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%d, want=1", len(args))
+		}
+		for _, el := range receiver.(*object.Array).Elements {
+			if objectsEqual(el, args[0]) {
+				return TRUE
+			}
+		}
+		return FALSE
+	},
+	"join": func(receiver object.Object, args ...object.Object) object.Object {
+		sep, err := stringArgument("join", args)
+		if err != nil {
+			return err
+		}
+		parts := []string{}
+		for _, el := range receiver.(*object.Array).Elements {
+			parts = append(parts, el.Inspect())
+		}
+		return &object.String{Value: strings.Join(parts, sep)}
+	},
+	"reverse": func(receiver object.Object, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError("wrong number of arguments. got=%d, want=0", len(args))
+		}
+		elements := receiver.(*object.Array).Elements
+		reversed := make([]object.Object, len(elements))
+		for i, el := range elements {
+			reversed[len(elements)-1-i] = el
+		}
+		return &object.Array{Elements: reversed}
+	},
+}
+
+var hashMethods = map[string]method{
+	"len": func(receiver object.Object, args ...object.Object) object.Object {
+		return builtins["len"].Fn(append([]object.Object{receiver}, args...)...)
+	},
+	"keys": func(receiver object.Object, args ...object.Object) object.Object {
+		return builtins["keys"].Fn(append([]object.Object{receiver}, args...)...)
+	},
+	"values": func(receiver object.Object, args ...object.Object) object.Object {
+		return builtins["values"].Fn(append([]object.Object{receiver}, args...)...)
+	},
+	"has": func(receiver object.Object, args ...object.Object) object.Object {
+		return builtins["has"].Fn(append([]object.Object{receiver}, args...)...)
+	},
+	"delete": func(receiver object.Object, args ...object.Object) object.Object {
+		return builtins["delete"].Fn(append([]object.Object{receiver}, args...)...)
+	},
+	"get": func(receiver object.Object, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError("wrong number of arguments. got=%d, want=2", len(args))
+		}
+		key, ok := args[0].(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", args[0].Type())
+		}
+		if value, ok := receiver.(*object.Hash).Get(key); ok {
+			return value
+		}
+		return args[1]
+	},
+}
+
+// stringArgument checks that args is a single STRING
+// argument to the method name and returns its value
+func stringArgument(name string, args []object.Object) (string, *object.Error) {
+	if len(args) != 1 {
+		return "", newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return "", newError("argument to `%s` must be STRING, got %s", name, args[0].Type())
+	}
+	return str.Value, nil
+}
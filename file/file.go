@@ -23,6 +23,11 @@ type File struct {
 	value string
 }
 
+// Name returns the name the file was opened with
+func (f *File) Name() string {
+	return f.name
+}
+
 func (f *File) String() string {
 	return f.value
 }
@@ -1,7 +1,10 @@
 package lexer
 
 import (
-	"strings"
+	"bytes"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
 	"zlang/token"
 )
 
@@ -10,37 +13,63 @@ type Lexer struct {
 	input        string
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
+	ch           rune // current char under examination
+
+	file   string // name of the source file, if any
+	line   int    // line of the current char
+	column int    // column of the current char
+
+	errors []string
+
+	// open brace counts for each string interpolation we are
+	// inside of, so the } that closes a ${ can resume the string
+	interpolations []int
 }
 
 // New returns a pointer to a new Lexer
 func New(input string) *Lexer {
+	return NewWithFile(input, "")
+}
 
-	var i = strings.Split(input, "\n")
-
-	for idx, line := range i {
-		if strings.HasPrefix(strings.TrimSpace(line), "//") {
-			i[idx] = ""
-		} else if strings.Contains(line, "//") {
-			stop := strings.Index(line, "//")
-			i[idx] = i[idx][0:stop]
-		}
-	}
-
-	l := &Lexer{input: strings.Join(i, "\n")}
+// NewWithFile returns a pointer to a new Lexer that
+// reports positions in the named file
+func NewWithFile(input string, file string) *Lexer {
+	l := &Lexer{input: input, file: file, line: 1}
 	l.readChar()
 	return l
 }
 
+// Errors returns the errors found while lexing so far
+func (l *Lexer) Errors() []string {
+	return l.errors
+}
+
+func (l *Lexer) errorAt(pos token.Position, format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if pos.IsValid() {
+		msg = pos.String() + ": " + msg
+	}
+	l.errors = append(l.errors, msg)
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	if l.readPosition <= len(l.input) {
+		l.column++
+	}
+
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 
 	l.position = l.readPosition
-	l.readPosition++
+	l.readPosition += width
 }
 
 // NextToken looks at the current char and
@@ -50,70 +79,136 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.skipWhitespace()
 
+	pos := l.currentPosition()
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.EQ, Literal: literal}
+			tok = l.newTwoCharToken(token.EQ)
+		} else if l.peekChar() == '>' {
+			tok = l.newTwoCharToken(token.ARROW)
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
 		tok = newToken(token.RPAREN, l.ch)
 	case '"':
+		tok.Type, tok.Literal = l.readString()
+	case '`':
 		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok.Literal = l.readRawString()
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.PLUS_ASSIGN)
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.MINUS_ASSIGN)
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.NOT_EQ, Literal: literal}
+			tok = l.newTwoCharToken(token.NOT_EQ)
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		if l.peekChar() == '/' {
+			tok.Type = token.COMMENT
+			tok.Literal = l.readLineComment()
+			tok.Pos = pos
+			return tok
+		} else if l.peekChar() == '*' {
+			tok.Type = token.COMMENT
+			tok.Literal = l.readBlockComment()
+		} else if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.SLASH_ASSIGN)
+		} else {
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.ASTERISK_ASSIGN)
+		} else if l.peekChar() == '*' {
+			tok = l.newTwoCharToken(token.POWER)
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
 	case '<':
 		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.LT_EQ, Literal: literal}
+			tok = l.newTwoCharToken(token.LT_EQ)
+		} else if l.peekChar() == '<' {
+			tok = l.newTwoCharToken(token.SHIFT_LEFT)
 		} else {
 			tok = newToken(token.LT, l.ch)
 		}
 	case '>':
 		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.GT_EQ, Literal: literal}
+			tok = l.newTwoCharToken(token.GT_EQ)
+		} else if l.peekChar() == '>' {
+			tok = l.newTwoCharToken(token.SHIFT_RIGHT)
 		} else {
 			tok = newToken(token.GT, l.ch)
 		}
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.newTwoCharToken(token.AND)
+		} else {
+			tok = newToken(token.AMPERSAND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.newTwoCharToken(token.OR)
+		} else {
+			tok = newToken(token.PIPE, l.ch)
+		}
+	case '^':
+		tok = newToken(token.CARET, l.ch)
+	case '~':
+		tok = newToken(token.TILDE, l.ch)
+	case '?':
+		tok = newToken(token.QUESTION, l.ch)
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '{':
+		if n := len(l.interpolations); n > 0 {
+			l.interpolations[n-1]++
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
-		tok = newToken(token.RBRACE, l.ch)
+		if n := len(l.interpolations); n > 0 && l.interpolations[n-1] == 0 {
+			// closes a ${, so the string carries on from here
+			l.interpolations = l.interpolations[:n-1]
+			tok.Type, tok.Literal = l.readString()
+		} else {
+			if n > 0 {
+				l.interpolations[n-1]--
+			}
+			tok = newToken(token.RBRACE, l.ch)
+		}
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
+	case '.':
+		if l.peekChar() == '.' && l.peekSecondChar() == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -121,10 +216,11 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
+			tok.Pos = pos
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -132,28 +228,97 @@ func (l *Lexer) NextToken() token.Token {
 	}
 
 	l.readChar()
+	tok.Pos = pos
 	return tok
 }
 
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{File: l.file, Line: l.line, Column: l.column}
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
 	}
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	}
-	return l.input[l.readPosition]
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return ch
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer or a float literal and returns
+// its token type. Integers may use 0x, 0o and 0b prefixes and
+// any number may use _ as a digit separator, e.g. 1_000_000
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	position := l.position
-	for isDigit(l.ch) {
+	tokenType := token.TokenType(token.INT)
+
+	if l.ch == '0' && isBasePrefix(l.peekChar()) {
+		l.readChar()
+		l.readChar()
+		for isHexDigit(l.ch) || l.ch == '_' {
+			l.readChar()
+		}
+		return tokenType, l.input[position:l.position]
+	}
+
+	l.readDigits()
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || (next == '+' || next == '-') && isDigit(l.peekSecondChar()) {
+			tokenType = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			l.readDigits()
+		}
+	}
+
+	return tokenType, l.input[position:l.position]
+}
+
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) || l.ch == '_' {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+}
+
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+func isHexDigit(ch rune) bool {
+	_, ok := hexValue(ch)
+	return ok
+}
+
+// peekSecondChar returns the char after the one peekChar returns
+func (l *Lexer) peekSecondChar() rune {
+	if l.readPosition >= len(l.input) {
+		return 0
+	}
+	_, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	if l.readPosition+width >= len(l.input) {
+		return 0
+	}
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition+width:])
+	return ch
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -164,25 +329,189 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+// readString reads a double quoted string literal, decoding
+// escape sequences. The lexer is left on the closing quote
+//
+// A ${ inside the string ends the segment early and the
+// STRING_PART token is returned with the lexer on the {. The
+// tokens of the embedded expression follow, and the } closing
+// it resumes the string as another STRING_PART or STRING
+func (l *Lexer) readString() (token.TokenType, string) {
+	start := l.currentPosition()
+	var out bytes.Buffer
+
+	for {
+		l.readChar()
+		switch l.ch {
+		case '"':
+			return token.STRING, out.String()
+		case 0:
+			l.errorAt(start, "unterminated string literal")
+			return token.STRING, out.String()
+		case '$':
+			if l.peekChar() != '{' {
+				out.WriteRune(l.ch)
+				continue
+			}
+			if l.peekSecondChar() == '}' {
+				l.errorAt(l.currentPosition(), "empty expression in string interpolation")
+			}
+			l.readChar()
+			l.interpolations = append(l.interpolations, 0)
+			return token.STRING_PART, out.String()
+		case '\\':
+			l.readEscape(&out)
+		default:
+			// copy the raw bytes so invalid UTF-8 survives untouched
+			out.WriteString(l.input[l.position:l.readPosition])
+		}
+	}
 }
 
-func (l *Lexer) readString() string {
+// readRawString reads a backtick string literal. Raw strings
+// may span lines and do not interpret escape sequences
+func (l *Lexer) readRawString() string {
+	start := l.currentPosition()
 	position := l.position + 1
+
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		if l.ch == '`' {
+			return l.input[position:l.position]
+		}
+		if l.ch == 0 {
+			l.errorAt(start, "unterminated raw string literal")
+			return l.input[position:l.position]
+		}
+	}
+}
+
+var escapes = map[rune]byte{
+	'a':  '\a',
+	'b':  '\b',
+	'f':  '\f',
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
+	'v':  '\v',
+	'0':  0,
+	'\\': '\\',
+	'\'': '\'',
+	'"':  '"',
+	'$':  '$',
+}
+
+// readEscape decodes the escape sequence starting at the
+// current backslash and writes it to out
+func (l *Lexer) readEscape(out *bytes.Buffer) {
+	pos := l.currentPosition()
+
+	if l.peekChar() == 0 {
+		return
+	}
+	l.readChar()
+
+	if ch, ok := escapes[l.ch]; ok {
+		out.WriteByte(ch)
+		return
+	}
+
+	switch l.ch {
+	case 'x':
+		if v, ok := l.readHex(2); ok {
+			out.WriteByte(byte(v))
+			return
+		}
+	case 'u':
+		if v, ok := l.readHex(4); ok && utf8.ValidRune(rune(v)) {
+			out.WriteRune(rune(v))
+			return
+		}
+	case 'U':
+		if v, ok := l.readHex(8); ok && utf8.ValidRune(rune(v)) {
+			out.WriteRune(rune(v))
+			return
+		}
+	default:
+		l.errorAt(pos, "unknown escape sequence \\%c", l.ch)
+		return
+	}
+
+	l.errorAt(pos, "invalid escape sequence in string literal")
+}
+
+// readHex consumes up to n hex digits following the current char
+func (l *Lexer) readHex(n int) (uint32, bool) {
+	var v uint32
+	for i := 0; i < n; i++ {
+		d, ok := hexValue(l.peekChar())
+		if !ok {
+			return 0, false
 		}
+		l.readChar()
+		v = v<<4 | d
+	}
+	return v, true
+}
+
+func hexValue(ch rune) (uint32, bool) {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return uint32(ch - '0'), true
+	case 'a' <= ch && ch <= 'f':
+		return uint32(ch-'a') + 10, true
+	case 'A' <= ch && ch <= 'F':
+		return uint32(ch-'A') + 10, true
+	}
+	return 0, false
+}
+
+// readLineComment reads a // comment up to, but
+// not including, the end of the line
+func (l *Lexer) readLineComment() string {
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+// readBlockComment reads a /* */ comment and leaves
+// the lexer on the closing slash
+func (l *Lexer) readBlockComment() string {
+	start := l.currentPosition()
+	position := l.position
+	l.readChar() // skip '*'
+	for {
+		l.readChar()
+		if l.ch == 0 {
+			l.errorAt(start, "unterminated comment")
+			return l.input[position:l.position]
+		}
+		if l.ch == '*' && l.peekChar() == '/' {
+			l.readChar()
+			return l.input[position:l.readPosition]
+		}
+	}
+}
+
+// newTwoCharToken reads the next char and returns a token
+// made of it and the current char, such as == or +=
+func (l *Lexer) newTwoCharToken(tokenType token.TokenType) token.Token {
+	ch := l.ch
+	l.readChar()
+	literal := string(ch) + string(l.ch)
+	return token.Token{Type: tokenType, Literal: literal}
+}
+
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
@@ -31,6 +31,13 @@ if (5 < 10) {
 [1, 2];
 10 <= 9;
 10 >= 9;
+{"foo": "bar"}
+x += 1; x -= 1; x *= 2; x /= 2;
+a && b || not c
+a % b ** c & d | e ^ ~f << g >> h
+match x { _ => 1 }
+[...rest]
+f(x)?
 `
 
 	tests := []struct {
@@ -120,6 +127,65 @@ if (5 < 10) {
 		{token.GT_EQ, ">="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.LBRACE, "{"},
+		{token.STRING, "foo"},
+		{token.COLON, ":"},
+		{token.STRING, "bar"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.NOT, "not"},
+		{token.IDENT, "c"},
+		{token.IDENT, "a"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "b"},
+		{token.POWER, "**"},
+		{token.IDENT, "c"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "d"},
+		{token.PIPE, "|"},
+		{token.IDENT, "e"},
+		{token.CARET, "^"},
+		{token.TILDE, "~"},
+		{token.IDENT, "f"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.IDENT, "g"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.IDENT, "h"},
+		{token.MATCH, "match"},
+		{token.IDENT, "x"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RBRACKET, "]"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.QUESTION, "?"},
 		{token.EOF, ""},
 	}
 
@@ -138,3 +204,294 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  x + y;
+"str"`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 2, 3},
+		{token.PLUS, 2, 5},
+		{token.IDENT, 2, 7},
+		{token.SEMICOLON, 2, 8},
+		{token.STRING, 3, 1},
+		{token.EOF, 3, 6},
+	}
+
+	l := NewWithFile(input, "test.z")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos.File != "test.z" {
+			t.Fatalf("tests[%d] - file wrong. expected=%q, got=%q", i, "test.z", tok.Pos.File)
+		}
+
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d", i,
+				tt.expectedLine, tt.expectedColumn, tok.Pos.Line, tok.Pos.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let url = "http://example.com"; // trailing
+/* block
+   comment */ 10 / 2
+/* unterminated`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "// leading comment"},
+		{token.LET, "let"},
+		{token.IDENT, "url"},
+		{token.ASSIGN, "="},
+		{token.STRING, "http://example.com"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "// trailing"},
+		{token.COMMENT, "/* block\n   comment */"},
+		{token.INT, "10"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.COMMENT, "/* unterminated"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	if len(l.Errors()) != 1 || l.Errors()[0] != "5:1: unterminated comment" {
+		t.Errorf("wrong lexer errors. got=%q", l.Errors())
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		expectedErrors  []string
+	}{
+		{`"plain"`, "plain", nil},
+		{`"say \"hi\""`, `say "hi"`, nil},
+		{`"a\tb\nc\\"`, "a\tb\nc\\", nil},
+		{`"\x41\u00e9\U0001F600"`, "A\u00e9\U0001F600", nil},
+		{"\"two\nlines\"", "two\nlines", nil},
+		{"`raw \\n ${x}\nstring`", "raw \\n ${x}\nstring", nil},
+		{`"bad \q escape"`, "bad  escape", []string{"1:6: unknown escape sequence \\q"}},
+		{`"\u12"`, "", []string{"1:2: invalid escape sequence in string literal"}},
+		{`let s = "open`, "open", []string{"1:9: unterminated string literal"}},
+		{"`open", "open", []string{"1:1: unterminated raw string literal"}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		tok := l.NextToken()
+		for tok.Type != token.STRING && tok.Type != token.EOF {
+			tok = l.NextToken()
+		}
+
+		if tok.Type != token.STRING {
+			t.Fatalf("no string token in %q", tt.input)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("literal wrong. expected=%q, got=%q", tt.expectedLiteral, tok.Literal)
+		}
+
+		if len(l.Errors()) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. expected=%q, got=%q",
+				tt.input, tt.expectedErrors, l.Errors())
+			continue
+		}
+
+		for i, msg := range tt.expectedErrors {
+			if l.Errors()[i] != msg {
+				t.Errorf("error %d wrong. expected=%q, got=%q", i, msg, l.Errors()[i])
+			}
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := `let názov = "日本"; názov + ñ`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "názov", 5},
+		{token.ASSIGN, "=", 11},
+		{token.STRING, "日本", 13},
+		{token.SEMICOLON, ";", 17},
+		{token.IDENT, "názov", 19},
+		{token.PLUS, "+", 25},
+		{token.IDENT, "ñ", 27},
+		{token.EOF, "", 28},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - column wrong. expected=%d, got=%d", i, tt.expectedColumn, tok.Pos.Column)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `5 1.5 0.25 1e3 1e-3 2.5E+2 3.foo 4e x1`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "5"},
+		{token.FLOAT, "1.5"},
+		{token.FLOAT, "0.25"},
+		{token.FLOAT, "1e3"},
+		{token.FLOAT, "1e-3"},
+		{token.FLOAT, "2.5E+2"},
+		{token.INT, "3"},
+		{token.DOT, "."},
+		{token.IDENT, "foo"},
+		{token.INT, "4"},
+		{token.IDENT, "e"},
+		{token.IDENT, "x"},
+		{token.INT, "1"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestIntegerLiteralPrefixes(t *testing.T) {
+	input := `0xff 0XAB_CD 0o755 0b1010 1_000_000 1_000.5`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "0xff"},
+		{token.INT, "0XAB_CD"},
+		{token.INT, "0o755"},
+		{token.INT, "0b1010"},
+		{token.INT, "1_000_000"},
+		{token.FLOAT, "1_000.5"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	input := `"length is ${len(arr)}." "${a}${ {"k": "}"}["k"] } \${x}" "$5"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING_PART, "length is "},
+		{token.IDENT, "len"},
+		{token.LPAREN, "("},
+		{token.IDENT, "arr"},
+		{token.RPAREN, ")"},
+		{token.STRING, "."},
+		{token.STRING_PART, ""},
+		{token.IDENT, "a"},
+		{token.STRING_PART, ""},
+		{token.LBRACE, "{"},
+		{token.STRING, "k"},
+		{token.COLON, ":"},
+		{token.STRING, "}"},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "k"},
+		{token.RBRACKET, "]"},
+		{token.STRING, " ${x}"},
+		{token.STRING, "$5"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected lexer errors. got=%q", l.Errors())
+	}
+}
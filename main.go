@@ -38,13 +38,22 @@ func main() {
 		fname := os.Args[1]
 		file := file.NewFile(fname)
 		env := object.NewEnvironment()
-		l := lexer.New(file.String())
+		l := lexer.NewWithFile(file.String(), file.Name())
 		p := parser.New(l)
 
 		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			for _, msg := range p.Errors() {
+				fmt.Fprintf(os.Stderr, "error: %s\n", msg)
+			}
+			os.Exit(1)
+		}
+		for _, msg := range p.Warnings() {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
+		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated.Inspect() != "" {
+		evaluated := evaluator.EvalFile(program, file.Name(), env)
+		if evaluated != nil && evaluated.Inspect() != "" {
 			fmt.Println(evaluated.Inspect())
 		}
 	}
@@ -4,19 +4,76 @@ package object
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	env.modules = outer.modules
+	env.deferred = outer.deferred
 	return env
 }
 
-// NewEnvironment returns an empty environment
+// NewFunctionEnvironment returns a new enclosed env for a
+// function call, with its own list of deferred functions
+func NewFunctionEnvironment(outer *Environment) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.deferred = &[]Object{}
+	return env
+}
+
+// NewEnvironment returns an empty environment. Each one
+// made this way starts a new interpreter with its own
+// module cache
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	return &Environment{store: s, outer: nil, modules: NewModuleCache()}
+}
+
+// NewModuleEnvironment returns an empty top level environment
+// for a module, sharing the module cache of e
+func (e *Environment) NewModuleEnvironment() *Environment {
+	s := make(map[string]Object)
+	return &Environment{store: s, outer: nil, modules: e.modules}
 }
 
 // Environment has a map of objects and names
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store    map[string]Object
+	outer    *Environment
+	modules  *ModuleCache
+	deferred *[]Object // shared by the scopes of one function call
+}
+
+// Modules returns the module cache of the interpreter e belongs to
+func (e *Environment) Modules() *ModuleCache {
+	return e.modules
+}
+
+// Defer records fn to be called without arguments when the
+// current function call returns. It reports false outside
+// a function
+func (e *Environment) Defer(fn Object) bool {
+	if e.deferred == nil {
+		return false
+	}
+	*e.deferred = append(*e.deferred, fn)
+	return true
+}
+
+// Deferred returns the functions deferred in the function
+// call e belongs to, in the order they were deferred
+func (e *Environment) Deferred() []Object {
+	if e.deferred == nil {
+		return nil
+	}
+	return *e.deferred
+}
+
+// ModuleCache holds the modules loaded by one interpreter
+type ModuleCache struct {
+	Loaded  map[string]*Module // by absolute path
+	Loading []string           // paths being loaded, innermost last
+}
+
+// NewModuleCache returns an empty module cache
+func NewModuleCache() *ModuleCache {
+	return &ModuleCache{Loaded: make(map[string]*Module)}
 }
 
 // Get returns object from environment store
@@ -33,3 +90,17 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+// Assign updates the nearest enclosing binding of name and
+// reports whether one was found. Unlike Set it never creates
+// a new binding
+func (e *Environment) Assign(name string, val Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
@@ -3,8 +3,12 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
 	"strings"
 	"zlang/ast"
+	"zlang/token"
 )
 
 // ObjectType is the type of object
@@ -12,14 +16,23 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
+	MODULE_OBJ       = "MODULE"
+	INSTANCE_OBJ     = "INSTANCE"
+	ENUM_OBJ         = "ENUM"
+	VARIANT_OBJ      = "VARIANT"
+	ERROR_VALUE_OBJ  = "ERROR_VALUE"
 )
 
 // Object is the base for all types
@@ -39,6 +52,30 @@ func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 // Inspect returns integer value as string
 func (i *Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
 
+// Float is a floating point type
+type Float struct {
+	Value float64
+}
+
+// Type returns object type of float
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// Inspect returns float value as string, always with a
+// decimal point or exponent so it never reads as an integer.
+// Only very large or very small values use an exponent
+func (f *Float) Inspect() string {
+	format := byte('g')
+	if abs := math.Abs(f.Value); abs == 0 || (abs >= 1e-4 && abs < 1e21) {
+		format = 'f'
+	}
+
+	s := strconv.FormatFloat(f.Value, format, -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
 // Boolean is a boolean type
 type Boolean struct {
 	Value bool
@@ -79,20 +116,89 @@ func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 // Inspect returns return value as string
 func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
 
-// Error is an error type
+// Break signals a break out of the innermost loop
+type Break struct{}
+
+// Type returns object type of break
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+
+// Inspect returns break as string
+func (b *Break) Inspect() string { return "break" }
+
+// Continue signals a jump to the next iteration of the innermost loop
+type Continue struct{}
+
+// Type returns object type of continue
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+
+// Inspect returns continue as string
+func (c *Continue) Inspect() string { return "continue" }
+
+// Error is an error type. Errors unwind evaluation until
+// they are caught or reach the top level
 type Error struct {
 	Message string
+	Kind    string         // empty for errors raised by the interpreter
+	Value   Object         // the thrown value, for errors raised by throw
+	Pos     token.Position // where the error was raised, if known
 }
 
 // Type returns object type of error
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 
 // Inspect returns error message as string
-func (e *Error) Inspect() string { return "bruh moment: " + e.Message }
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "bruh moment: " + e.Pos.String() + ": " + e.Message
+	}
+	return "bruh moment: " + e.Message
+}
+
+// ErrorValue is an error held as an ordinary value, as bound
+// by catch or made by error(). It does not unwind evaluation,
+// and throwing it raises the error it holds
+type ErrorValue struct {
+	Err *Error
+}
+
+// Type returns object type of error value
+func (e *ErrorValue) Type() ObjectType { return ERROR_VALUE_OBJ }
+
+// TypeName returns the name type() reports for error values
+func (e *ErrorValue) TypeName() string { return "ERROR" }
+
+// Kind returns the kind of the error, which defaults to
+// RuntimeError for errors raised by the interpreter
+func (e *ErrorValue) Kind() string {
+	if e.Err.Kind == "" {
+		return "RuntimeError"
+	}
+	return e.Err.Kind
+}
+
+// Inspect returns error value as string (RuntimeError: message)
+func (e *ErrorValue) Inspect() string { return e.Kind() + ": " + e.Err.Message }
+
+// Field returns the message and kind of the error, and the
+// thrown value for errors raised by throw
+func (e *ErrorValue) Field(name string) (Object, bool) {
+	switch name {
+	case "message":
+		return &String{Value: e.Err.Message}, true
+	case "kind":
+		return &String{Value: e.Kind()}, true
+	case "value":
+		return e.Err.Value, e.Err.Value != nil
+	}
+	return nil, false
+}
 
 // Function is a function type
 type Function struct {
+	Name       string // empty for anonymous functions
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression
+	Rest       *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -105,11 +211,21 @@ func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
+	for i, p := range f.Parameters {
+		if i < len(f.Defaults) && f.Defaults[i] != nil {
+			params = append(params, p.String()+" = "+f.Defaults[i].String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
 	}
 
 	out.WriteString("fn")
+	if f.Name != "" {
+		out.WriteString(" " + f.Name)
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
@@ -158,11 +274,7 @@ func (ao *Array) Inspect() string {
 
 	elements := []string{}
 	for _, e := range ao.Elements {
-		s := e.Inspect()
-		if e.Type() == STRING_OBJ {
-			s = fmt.Sprintf(`"%s"`, e.Inspect())
-		}
-		elements = append(elements, s)
+		elements = append(elements, inspectElement(e))
 	}
 
 	out.WriteString("[")
@@ -171,3 +283,278 @@ func (ao *Array) Inspect() string {
 
 	return out.String()
 }
+
+// inspectElement inspects a value held in a collection,
+// quoting strings so they stand out from other values
+func inspectElement(obj Object) string {
+	if obj.Type() == STRING_OBJ {
+		return strconv.Quote(obj.Inspect())
+	}
+	return obj.Inspect()
+}
+
+// HashKey identifies a hashable value in a Hash
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by objects usable as hash keys
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+// HashKey returns the hash key of an integer
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+// HashKey returns the hash key of a boolean
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+// HashKey returns the hash key of a string
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// Fielder is implemented by record-like objects whose
+// named fields can be read with obj.field
+type Fielder interface {
+	Object
+	Field(name string) (Object, bool)
+}
+
+// FieldSetter is implemented by record-like objects whose
+// fields can be written with obj.field = value
+type FieldSetter interface {
+	Fielder
+	SetField(name string, value Object) bool
+}
+
+// TypeNamer is implemented by objects that report a user
+// defined type name, such as a struct name, to type()
+type TypeNamer interface {
+	Object
+	TypeName() string
+}
+
+// HashPair is a key and value stored in a Hash
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash is a key/value type that remembers insertion order.
+// Use NewHash and the Set, Get and Delete methods so Pairs
+// and Keys stay in step
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	Keys  []HashKey // insertion order
+}
+
+// NewHash returns an empty hash
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Type returns object type of hash
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+
+// Get returns the value stored under key
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.Pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+// Field returns the value stored under the string key name
+func (h *Hash) Field(name string) (Object, bool) {
+	return h.Get(&String{Value: name})
+}
+
+// SetField stores value under the string key name
+func (h *Hash) SetField(name string, value Object) bool {
+	h.Set(&String{Value: name}, value)
+	return true
+}
+
+// Set stores value under key, keeping the original
+// position of the key if it is already present
+func (h *Hash) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+	if _, ok := h.Pairs[hashKey]; !ok {
+		h.Keys = append(h.Keys, hashKey)
+	}
+	h.Pairs[hashKey] = HashPair{Key: key, Value: value}
+}
+
+// Delete removes key and reports whether it was present
+func (h *Hash) Delete(key Hashable) bool {
+	hashKey := key.HashKey()
+	if _, ok := h.Pairs[hashKey]; !ok {
+		return false
+	}
+
+	delete(h.Pairs, hashKey)
+	for i, k := range h.Keys {
+		if k == hashKey {
+			h.Keys = append(h.Keys[:i], h.Keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Inspect returns hash as string
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, key := range h.Keys {
+		pair := h.Pairs[key]
+		pairs = append(pairs, inspectElement(pair.Key)+": "+inspectElement(pair.Value))
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Module is a source file loaded by an import statement.
+// Its exported bindings, those not starting with an
+// underscore, are read as fields (strs.upper)
+type Module struct {
+	Name string
+	Path string
+	Env  *Environment
+}
+
+// Type returns object type of module
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+
+// Inspect returns module as string
+func (m *Module) Inspect() string { return "<module " + m.Name + ">" }
+
+// Field returns the exported binding called name
+func (m *Module) Field(name string) (Object, bool) {
+	if strings.HasPrefix(name, "_") {
+		return nil, false
+	}
+	return m.Env.Get(name)
+}
+
+// Instance is a value of a struct type, with its fields
+// kept in declaration order
+type Instance struct {
+	Name   string
+	Fields []string
+	Values map[string]Object
+}
+
+// Type returns object type of instance
+func (i *Instance) Type() ObjectType { return INSTANCE_OBJ }
+
+// TypeName returns the name of the struct
+func (i *Instance) TypeName() string { return i.Name }
+
+// Inspect returns instance as string (Point{x: 1, y: 2})
+func (i *Instance) Inspect() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, name := range i.Fields {
+		fields = append(fields, name+": "+inspectElement(i.Values[name]))
+	}
+
+	out.WriteString(i.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Field returns the value of the field called name
+func (i *Instance) Field(name string) (Object, bool) {
+	value, ok := i.Values[name]
+	return value, ok
+}
+
+// SetField sets a declared field and reports whether
+// the struct has a field called name
+func (i *Instance) SetField(name string, value Object) bool {
+	if _, ok := i.Values[name]; !ok {
+		return false
+	}
+	i.Values[name] = value
+	return true
+}
+
+// Enum is a declared enum type. Its variants are read as
+// fields: constructors for variants with a payload, and the
+// variant itself for those without
+type Enum struct {
+	Name     string
+	Variants map[string]Object
+}
+
+// Type returns object type of enum
+func (e *Enum) Type() ObjectType { return ENUM_OBJ }
+
+// Inspect returns enum as string
+func (e *Enum) Inspect() string { return "<enum " + e.Name + ">" }
+
+// Field returns the variant called name
+func (e *Enum) Field(name string) (Object, bool) {
+	variant, ok := e.Variants[name]
+	return variant, ok
+}
+
+// Variant is a value of an enum type. Variants without a
+// payload are shared, so there is one of each per enum
+type Variant struct {
+	Enum   *Enum
+	Name   string
+	Fields []string
+	Values []Object
+}
+
+// Type returns object type of variant
+func (v *Variant) Type() ObjectType { return VARIANT_OBJ }
+
+// TypeName returns the enum and variant name (Shape.Circle)
+func (v *Variant) TypeName() string { return v.Enum.Name + "." + v.Name }
+
+// Inspect returns variant as string (Shape.Rect(2, 3))
+func (v *Variant) Inspect() string {
+	if v.Fields == nil {
+		return v.TypeName()
+	}
+
+	values := []string{}
+	for _, value := range v.Values {
+		values = append(values, inspectElement(value))
+	}
+
+	return v.TypeName() + "(" + strings.Join(values, ", ") + ")"
+}
+
+// Field returns the payload value called name
+func (v *Variant) Field(name string) (Object, bool) {
+	for i, field := range v.Fields {
+		if field == name {
+			return v.Values[i], true
+		}
+	}
+	return nil, false
+}
@@ -139,6 +139,90 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestPrefixedIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xff;", 255},
+		{"0o17;", 15},
+		{"0b1010;", 10},
+		{"1_000_000;", 1000000},
+		{"0xFF_FF;", 65535},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %d. got=%d", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestInvalidIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let x =\n  9223372036854775808;", "2:3: integer literal 9223372036854775808 overflows a 64-bit integer"},
+		{"0x1_0000_0000_0000_0000;", "1:1: integer literal 0x1_0000_0000_0000_0000 overflows a 64-bit integer"},
+		{"1__0;", "1:1: could not parse \"1__0\" as integer"},
+		{"0b102;", "1:1: could not parse \"0b102\" as integer"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5;", 1.5},
+		{"0.001;", 0.001},
+		{"1e-3;", 0.001},
+		{"2.5e2;", 250},
+		{"1_000.5;", 1000.5},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -151,6 +235,9 @@ func TestParsingPrefixExpressions(t *testing.T) {
 		{"-foobar;", "-", "foobar"},
 		{"!true;", "!", true},
 		{"!false;", "!", false},
+		{"not true;", "not", true},
+		{"not foobar;", "not", "foobar"},
+		{"~5;", "~", 5},
 	}
 
 	for _, tt := range prefixTests {
@@ -212,6 +299,8 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
+		{"true && false", true, "&&", false},
+		{"foobar || barfoo", "foobar", "||", "barfoo"},
 	}
 
 	for _, tt := range infixTests {
@@ -351,6 +440,78 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"-a.b * c.d(e)",
+			"((-(a.b)) * (c.d)(e))",
+		},
+		{
+			"a.b.c[0].d",
+			"((((a.b).c)[0]).d)",
+		},
+		{
+			"s.trim().upper() + x",
+			"(((s.trim)().upper)() + x)",
+		},
+		{
+			"fn() { f(x)? + g(y)?.z }",
+			"fn() ((f(x)?) + ((g(y)?).z))",
+		},
+		{
+			"fn() { -a? }",
+			"fn() (-(a?))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a < b && b < c == true",
+			"((a < b) && ((b < c) == true))",
+		},
+		{
+			"not a && !b",
+			"((not a) && (!b))",
+		},
+		{
+			"a + b % c",
+			"(a + (b % c))",
+		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"-2 ** 2",
+			"(-(2 ** 2))",
+		},
+		{
+			"a * b ** c",
+			"(a * (b ** c))",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b == c",
+			"((a & b) == c)",
+		},
+		{
+			"1 << a + b",
+			"(1 << (a + b))",
+		},
+		{
+			"a >> 1 & mask",
+			"((a >> 1) & mask)",
+		},
+		{
+			"~a & b",
+			"((~a) & b)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -566,6 +727,8 @@ func TestFunctionParameterParsing(t *testing.T) {
 		{input: "fn() {};", expectedParams: []string{}},
 		{input: "fn(x) {};", expectedParams: []string{"x"}},
 		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+		{input: "fn(x, y = 10) {};", expectedParams: []string{"x", "y"}},
+		{input: "fn(x, ...rest) {};", expectedParams: []string{"x"}},
 	}
 
 	for _, tt := range tests {
@@ -588,203 +751,1148 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
-func TestCallExpressionParsing(t *testing.T) {
-	input := "add(1, 2 * 3, 4 + 5);"
-
-	l := lexer.New(input)
-	p := New(l)
-	program := p.ParseProgram()
-	checkParserErrors(t, p)
-
-	if len(program.Statements) != 1 {
-		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
-			1, len(program.Statements))
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x, y = 10) { x }", "fn(x, y = 10) x"},
+		{"fn(x = 1 + 2, y = x) { x }", "fn(x = (1 + 2), y = x) x"},
+		{"fn(first, ...rest) { rest }", "fn(first, ...rest) rest"},
+		{"fn(...args) { args }", "fn(...args) args"},
+		{"fn(x, y = 1, ...rest) { x }", "fn(x, y = 1, ...rest) x"},
 	}
 
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf("stmt is not ast.ExpressionStatement. got=%T",
-			program.Statements[0])
-	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
 
-	exp, ok := stmt.Expression.(*ast.CallExpression)
-	if !ok {
-		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
-			stmt.Expression)
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
 	}
 
-	if !testIdentifier(t, exp.Function, "add") {
-		return
-	}
+	l := lexer.New("fn(x, y = 10, ...rest) {}")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
 
-	if len(exp.Arguments) != 3 {
-		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if function.Defaults[0] != nil {
+		t.Errorf("function.Defaults[0] is not nil. got=%s", function.Defaults[0])
 	}
-
-	testLiteralExpression(t, exp.Arguments[0], 1)
-	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
-	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+	testLiteralExpression(t, function.Defaults[1], 10)
+	testIdentifier(t, function.Rest, "rest")
 }
 
-func TestCallExpressionParameterParsing(t *testing.T) {
+func TestInvalidFunctionParameters(t *testing.T) {
 	tests := []struct {
 		input         string
-		expectedIdent string
-		expectedArgs  []string
+		expectedError string
 	}{
-		{
-			input:         "add();",
-			expectedIdent: "add",
-			expectedArgs:  []string{},
-		},
-		{
-			input:         "add(1);",
-			expectedIdent: "add",
-			expectedArgs:  []string{"1"},
-		},
-		{
-			input:         "add(1, 2 * 3, 4 + 5);",
-			expectedIdent: "add",
-			expectedArgs:  []string{"1", "(2 * 3)", "(4 + 5)"},
-		},
+		{"fn(x = 1, y) {}", "1:11: parameter y without a default follows one with a default"},
+		{"fn(...rest, x) {}", "1:11: expected next token to be ), got , instead"},
+		{"fn(1) {}", "1:4: expected parameter name, got INT"},
+		{"fn(x,) {}", "1:6: expected parameter name, got )"},
 	}
 
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
 		p := New(l)
-		program := p.ParseProgram()
-		checkParserErrors(t, p)
-
-		stmt := program.Statements[0].(*ast.ExpressionStatement)
-		exp, ok := stmt.Expression.(*ast.CallExpression)
-		if !ok {
-			t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
-				stmt.Expression)
-		}
-
-		if !testIdentifier(t, exp.Function, tt.expectedIdent) {
-			return
-		}
+		p.ParseProgram()
 
-		if len(exp.Arguments) != len(tt.expectedArgs) {
-			t.Fatalf("wrong number of arguments. want=%d, got=%d",
-				len(tt.expectedArgs), len(exp.Arguments))
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
 		}
-
-		for i, arg := range tt.expectedArgs {
-			if exp.Arguments[i].String() != arg {
-				t.Errorf("argument %d wrong. want=%q, got=%q", i,
-					arg, exp.Arguments[i].String())
-			}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
 		}
 	}
 }
 
-func TestStringLiteralExpression(t *testing.T) {
-	input := `"hello, world";`
+func TestFunctionStatement(t *testing.T) {
+	input := `fn add(x, y = 1) { x + y }; fn() { 1 }`
 
 	l := lexer.New(input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
-	stmt := program.Statements[0].(*ast.ExpressionStatement)
-	literal, ok := stmt.Expression.(*ast.StringLiteral)
-	if !ok {
-		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
 	}
 
-	if literal.Value != "hello, world" {
-		t.Errorf("literal.Value not %q. got=%q", "hello, world", literal.Value)
+	stmt, ok := program.Statements[0].(*ast.FunctionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.FunctionStatement. got=%T",
+			program.Statements[0])
 	}
-}
 
-func TestParsingArrayLiterals(t *testing.T) {
-	input := "[1, 2 * 2, 3 + 3]"
+	if !testIdentifier(t, stmt.Name, "add") {
+		return
+	}
 
-	l := lexer.New(input)
-	p := New(l)
-	program := p.ParseProgram()
-	checkParserErrors(t, p)
+	if stmt.Function.Name != "add" {
+		t.Errorf("stmt.Function.Name not %q. got=%q", "add", stmt.Function.Name)
+	}
 
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
-	array, ok := stmt.Expression.(*ast.ArrayLiteral)
-	if !ok {
-		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	if len(stmt.Function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d\n",
+			len(stmt.Function.Parameters))
 	}
 
-	if len(array.Elements) != 3 {
-		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	if _, ok := program.Statements[1].(*ast.ExpressionStatement); !ok {
+		t.Fatalf("program.Statements[1] is not ast.ExpressionStatement. got=%T",
+			program.Statements[1])
 	}
 
-	testIntegerLiteral(t, array.Elements[0], 1)
-	testInfixExpression(t, array.Elements[1], 2, "*", 2)
-	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+	expected := "fn add(x, y = 1) (x + y)fn() 1"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
 }
 
-func TestParsingIndexExpressions(t *testing.T) {
-	input := "myArray[1 + 1]"
+func TestMemberExpressionParsing(t *testing.T) {
+	input := "person.name"
 
 	l := lexer.New(input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
-	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MemberExpression)
 	if !ok {
-		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+		t.Fatalf("stmt.Expression is not ast.MemberExpression. got=%T", stmt.Expression)
 	}
 
-	if !testIdentifier(t, indexExp.Left, "myArray") {
+	if !testIdentifier(t, exp.Object, "person") {
 		return
 	}
 
-	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+	if !testIdentifier(t, exp.Property, "name") {
 		return
 	}
-}
 
-func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
-	if s.TokenLiteral() != "let" {
-		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
-		return false
-	}
+	l = lexer.New("person.1")
+	p = New(l)
+	p.ParseProgram()
 
-	letStmt, ok := s.(*ast.LetStatement)
-	if !ok {
-		t.Errorf("s not *ast.LetStatement. got=%T", s)
-		return false
+	errors := p.Errors()
+	expected := "1:8: expected next token to be IDENT, got INT instead"
+	if len(errors) == 0 || errors[0] != expected {
+		t.Errorf("wrong errors. expected=%q, got=%v", expected, errors)
 	}
+}
 
-	if letStmt.Name.Value != name {
-		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, letStmt.Name.Value)
-		return false
+func TestImportStatements(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedPath string
+		expectedName string
+	}{
+		{`import "lib/strings.z" as strs;`, "lib/strings.z", "strs"},
+		{`import "lib/strings.z"`, "lib/strings.z", "strings"},
+		{`import "util.z" as as`, "util.z", "as"},
 	}
 
-	if letStmt.Name.TokenLiteral() != name {
-		t.Errorf("letStmt.Name.TokenLiteral() not '%s'. got=%s",
-			name, letStmt.Name.TokenLiteral())
-		return false
-	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
 
-	return true
-}
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements))
+		}
 
-func testInfixExpression(t *testing.T, exp ast.Expression, left interface{},
-	operator string, right interface{}) bool {
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ImportStatement. got=%T",
+				program.Statements[0])
+		}
 
-	opExp, ok := exp.(*ast.InfixExpression)
-	if !ok {
-		t.Errorf("exp is not ast.InfixExpression. got=%T(%s)", exp, exp)
-		return false
-	}
+		if stmt.Path.Value != tt.expectedPath {
+			t.Errorf("stmt.Path.Value not %q. got=%q", tt.expectedPath, stmt.Path.Value)
+		}
 
-	if !testLiteralExpression(t, opExp.Left, left) {
-		return false
+		if !testIdentifier(t, stmt.Name, tt.expectedName) {
+			return
+		}
 	}
 
-	if opExp.Operator != operator {
+	l := lexer.New("import strs")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	expected := "1:8: expected next token to be STRING, got IDENT instead"
+	if len(errors) == 0 || errors[0] != expected {
+		t.Errorf("wrong errors. expected=%q, got=%v", expected, errors)
+	}
+}
+
+func TestStructStatements(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedName   string
+		expectedFields []string
+	}{
+		{"struct Point { x, y }", "Point", []string{"x", "y"}},
+		{"struct Empty {}", "Empty", []string{}},
+		{"struct Person {\n\tname,\n\tage,\n}", "Person", []string{"name", "age"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.StructStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.StructStatement. got=%T",
+				program.Statements[0])
+		}
+
+		if !testIdentifier(t, stmt.Name, tt.expectedName) {
+			return
+		}
+
+		if len(stmt.Fields) != len(tt.expectedFields) {
+			t.Fatalf("wrong number of fields. want %d, got=%d", len(tt.expectedFields), len(stmt.Fields))
+		}
+		for i, field := range tt.expectedFields {
+			testIdentifier(t, stmt.Fields[i], field)
+		}
+	}
+
+	errorTests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"struct Point { x, x }", "1:19: duplicate field x in struct Point"},
+		{"struct { x }", "1:8: expected next token to be IDENT, got { instead"},
+		{"struct Point { x y }", "1:18: expected next token to be ,, got IDENT instead"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestEnumStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"enum Shape { Circle(r), Rect(w, h), Empty }", "enum Shape { Circle(r), Rect(w, h), Empty }"},
+		{"enum Light {\n\tRed,\n\tGreen,\n}", "enum Light { Red, Green }"},
+		{"enum Unit { Nothing() }", "enum Unit { Nothing() }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements))
+		}
+
+		if _, ok := program.Statements[0].(*ast.EnumStatement); !ok {
+			t.Fatalf("program.Statements[0] is not ast.EnumStatement. got=%T",
+				program.Statements[0])
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("enum Shape { Circle(r), Rect(w, h), Empty }")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.EnumStatement)
+	if len(stmt.Variants) != 3 {
+		t.Fatalf("wrong number of variants. want 3, got=%d", len(stmt.Variants))
+	}
+	testIdentifier(t, stmt.Variants[1].Name, "Rect")
+	testIdentifier(t, stmt.Variants[1].Fields[1], "h")
+	if stmt.Variants[2].Fields != nil {
+		t.Errorf("payload-less variant has fields. got=%v", stmt.Variants[2].Fields)
+	}
+
+	errorTests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"enum Light { Red, Red }", "1:19: duplicate variant Red in enum Light"},
+		{"enum Shape { Circle(1) }", "1:21: expected next token to be IDENT, got INT instead"},
+		{"enum Shape { Circle(r) Empty }", "1:24: expected next token to be ,, got IDENT instead"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestCallExpressionParsing(t *testing.T) {
+	input := "add(1, 2 * 3, 4 + 5);"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
+			stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Function, "add") {
+		return
+	}
+
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+
+	testLiteralExpression(t, exp.Arguments[0], 1)
+	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
+	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+}
+
+func TestCallExpressionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedIdent string
+		expectedArgs  []string
+	}{
+		{
+			input:         "add();",
+			expectedIdent: "add",
+			expectedArgs:  []string{},
+		},
+		{
+			input:         "add(1);",
+			expectedIdent: "add",
+			expectedArgs:  []string{"1"},
+		},
+		{
+			input:         "add(1, 2 * 3, 4 + 5);",
+			expectedIdent: "add",
+			expectedArgs:  []string{"1", "(2 * 3)", "(4 + 5)"},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.CallExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
+				stmt.Expression)
+		}
+
+		if !testIdentifier(t, exp.Function, tt.expectedIdent) {
+			return
+		}
+
+		if len(exp.Arguments) != len(tt.expectedArgs) {
+			t.Fatalf("wrong number of arguments. want=%d, got=%d",
+				len(tt.expectedArgs), len(exp.Arguments))
+		}
+
+		for i, arg := range tt.expectedArgs {
+			if exp.Arguments[i].String() != arg {
+				t.Errorf("argument %d wrong. want=%q, got=%q", i,
+					arg, exp.Arguments[i].String())
+			}
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello, world";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != "hello, world" {
+		t.Errorf("literal.Value not %q. got=%q", "hello, world", literal.Value)
+	}
+}
+
+func TestStringLiteralRoundTrip(t *testing.T) {
+	inputs := []string{
+		`let s = "plain";`,
+		`let s = "tab\there \"quoted\" back\\slash";`,
+		`let s = "line\nbreak";`,
+		`let s = "cost: \${x}";`,
+		`let s = "cost: ${x} \${y}";`,
+	}
+
+	for _, input := range inputs {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != input {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", input, program.String())
+		}
+	}
+}
+
+func TestInterpolatedStringParsing(t *testing.T) {
+	input := `"length is ${len(arr)}, sum ${a + b}!"`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+
+	if len(str.Parts) != 5 {
+		t.Fatalf("wrong number of parts. want=5, got=%d", len(str.Parts))
+	}
+
+	if lit, ok := str.Parts[0].(*ast.StringLiteral); !ok || lit.Value != "length is " {
+		t.Errorf("parts[0] wrong. got=%T (%s)", str.Parts[0], str.Parts[0])
+	}
+	if str.Parts[1].String() != "len(arr)" {
+		t.Errorf("parts[1] wrong. got=%q", str.Parts[1].String())
+	}
+	testInfixExpression(t, str.Parts[3], "a", "+", "b")
+
+	if str.String() != `"length is ${len(arr)}, sum ${(a + b)}!"` {
+		t.Errorf("str.String() wrong. got=%q", str.String())
+	}
+}
+
+func TestInterpolatedStringErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`"a ${}"`, "1:4: empty expression in string interpolation"},
+		{`"a ${b"`, "1:7: unterminated string literal"},
+		{`"a ${b c}"`, "1:8: expected next token to be STRING, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestLexerErrorsAreReported(t *testing.T) {
+	l := lexer.New(`let s = "open;`)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != "1:9: unterminated string literal" {
+		t.Errorf("expected unterminated string error first. got=%q", errors)
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+}
+
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a[1:2]", "(a[1:2])"},
+		{"a[1:]", "(a[1:])"},
+		{"a[:2]", "(a[:2])"},
+		{"a[:]", "(a[:])"},
+		{"a[::]", "(a[:])"},
+		{"a[::2]", "(a[::2])"},
+		{"a[1:2:3]", "(a[1:2:3])"},
+		{"a[i + 1:-1:-1]", "(a[(i + 1):(-1):(-1)])"},
+		{"a[1:][0]", "((a[1:])[0])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("a[1:2:3]")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	slice, ok := stmt.Expression.(*ast.SliceExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.SliceExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, slice.Left, "a")
+	testIntegerLiteral(t, slice.Start, 1)
+	testIntegerLiteral(t, slice.End, 2)
+	testIntegerLiteral(t, slice.Step, 3)
+}
+
+func TestComments(t *testing.T) {
+	input := `// header
+let x = 5; /* inline */ let y =
+	// between tokens
+	x;`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != "let x = 5;let y = x;" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+
+	expected := []string{"// header", "/* inline */", "// between tokens"}
+	if len(program.Comments) != len(expected) {
+		t.Fatalf("wrong number of comments. want=%d, got=%d",
+			len(expected), len(program.Comments))
+	}
+
+	for i, text := range expected {
+		if program.Comments[i].String() != text {
+			t.Errorf("comment %d wrong. want=%q, got=%q", i,
+				text, program.Comments[i].String())
+		}
+	}
+
+	if program.Comments[2].Pos().Line != 3 {
+		t.Errorf("comment 2 line wrong. want=3, got=%d", program.Comments[2].Pos().Line)
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let x 5;", "1:7: expected next token to be =, got INT instead"},
+		{"let x = 1;\n  let = 2;", "2:7: expected next token to be IDENT, got = instead"},
+		{"1 +\n  ;", "2:3: no prefix parse function for ; found"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{}`, `{}`},
+		{`{"one": 1, "two": 2, "three": 3}`, `{"one": 1, "two": 2, "three": 3}`},
+		{`{"name": "bob", 1: true}`, `{"name": "bob", 1: true}`},
+		{`{"one": 0 + 1, two: 10 - 8, }`, `{"one": (0 + 1), two: (10 - 8)}`},
+		{`{true: {"nested": [1]}}`, `{true: {"nested": [1]}}`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		if !ok {
+			t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+		}
+
+		if len(hash.Keys) != len(hash.Values) {
+			t.Fatalf("hash.Keys and hash.Values differ in length. %d != %d",
+				len(hash.Keys), len(hash.Values))
+		}
+
+		if hash.String() != tt.expected {
+			t.Errorf("hash.String() wrong. expected=%q, got=%q", tt.expected, hash.String())
+		}
+	}
+}
+
+func TestAssignStatements(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedTarget   string
+		expectedOperator string
+		expectedValue    string
+	}{
+		{"x = 5;", "x", "=", "5"},
+		{"x += y * 2", "x", "+=", "(y * 2)"},
+		{"x -= 1;", "x", "-=", "1"},
+		{"x *= 3;", "x", "*=", "3"},
+		{"x /= 4;", "x", "/=", "4"},
+		{"arr[i + 1] = fn(x) { x };", "(arr[(i + 1)])", "=", "fn(x) x"},
+		{`h["k"] += 1`, `(h["k"])`, "+=", "1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.AssignStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.AssignStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.Target.String() != tt.expectedTarget {
+			t.Errorf("target wrong. expected=%q, got=%q", tt.expectedTarget, stmt.Target.String())
+		}
+		if stmt.Operator != tt.expectedOperator {
+			t.Errorf("operator wrong. expected=%q, got=%q", tt.expectedOperator, stmt.Operator)
+		}
+		if stmt.Value.String() != tt.expectedValue {
+			t.Errorf("value wrong. expected=%q, got=%q", tt.expectedValue, stmt.Value.String())
+		}
+	}
+}
+
+func TestInvalidAssignTargets(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"1 = 2;", "1:3: cannot assign to 1"},
+		{"f() += 1;", "1:5: cannot assign to f()"},
+		{"p.x() = 1;", "1:7: cannot assign to (p.x)()"},
+		{"if (x = 1) { }", "1:7: expected next token to be ), got = instead"},
+		{"!# = 1", "1:2: no prefix parse function for ILLEGAL found"},
+		{"-+ = 1", "1:2: no prefix parse function for + found"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestLoopStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { x; }", "while(x < 10) x"},
+		{"for (let i = 0; i < 10; let i = i + 1) { i; }", "for (let i = 0; (i < 10); let i = (i + 1)) i"},
+		{"for (;;) { break; }", "for (; ; ) break;"},
+		{"for (i = 0; i < 10; i += 1) { }", "for (i = 0; (i < 10); i += 1) "},
+		{"for (; i < 3;) { continue }", "for (; (i < 3); ) continue;"},
+		{"while (true) { if (x) { break; } continue; }", "whiletrue ifx break;continue;"},
+		{"while (x < 10) { x; };", "while(x < 10) x"},
+		{"for (;;) { break; };", "for (; ; ) break;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements))
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"break;", "1:1: break outside of a loop"},
+		{"if (true) { continue; }", "1:13: continue outside of a loop"},
+		{"while (true) { let f = fn() { break; }; }", "1:31: break outside of a loop"},
+		{"for (let i = 0 i < 1;) {}", "1:16: expected next token to be ;, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestSwitchExpressionParsing(t *testing.T) {
+	input := `switch (x) { case 1, 2 { a } case y { b } default { c } }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.SwitchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.SwitchExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Subject, "x") {
+		return
+	}
+
+	if len(exp.Cases) != 2 {
+		t.Fatalf("exp.Cases does not contain 2 cases. got=%d", len(exp.Cases))
+	}
+
+	if len(exp.Cases[0].Values) != 2 {
+		t.Fatalf("exp.Cases[0].Values does not contain 2 values. got=%d", len(exp.Cases[0].Values))
+	}
+	testLiteralExpression(t, exp.Cases[0].Values[0], 1)
+	testLiteralExpression(t, exp.Cases[0].Values[1], 2)
+	testLiteralExpression(t, exp.Cases[1].Values[0], "y")
+
+	if exp.Default == nil {
+		t.Fatalf("exp.Default is nil")
+	}
+
+	expected := "switchx { case 1, 2 { a } case y { b } default { c } }"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x) { a } else if (y) { b } else { c }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("alternative is not 1 statement. got=%d", len(exp.Alternative.Statements))
+	}
+
+	nested, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("alternative statement is not ast.ExpressionStatement. got=%T",
+			exp.Alternative.Statements[0])
+	}
+
+	inner, ok := nested.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("nested expression is not ast.IfExpression. got=%T", nested.Expression)
+	}
+
+	if !testIdentifier(t, inner.Condition, "y") {
+		return
+	}
+
+	if inner.Alternative == nil {
+		t.Fatalf("inner.Alternative is nil")
+	}
+}
+
+func TestSwitchErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"switch (x) { 1 { a } }", "1:14: expected case or default in switch, got INT"},
+		{"switch (x) { default { a } default { b } }", "1:28: multiple defaults in switch"},
+		{"switch x { }", "1:8: expected next token to be (, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match x { 0 => a, _ => b }", "match x { 0 => a, _ => b }"},
+		{"match x { [a, b] => a + b, [] => 0, _ => -1 }", "match x { [a, b] => (a + b), [] => 0, _ => (-1) }"},
+		{"match x { n if n > 0 => n, _ => 0, }", "match x { n if (n > 0) => n, _ => 0 }"},
+		{`match x { -1 => "neg", [[a], "b", true] => a, _ => { let y = 1; y } }`,
+			`match x { (-1) => "neg", [[a], "b", true] => a, _ => let y = 1;y }`},
+		{"match x { 1 => { a } 2 => { b } _ => c }", "match x { 1 => a, 2 => b, _ => c }"},
+		{"match x { [a, ...rest] => rest, {name} => name, _ => x }", "match x { [a, ...rest] => rest, {name} => name, _ => x }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements))
+		}
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.MatchExpression); !ok {
+			t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+
+		if len(p.Warnings()) != 0 {
+			t.Errorf("unexpected warnings for %q: %v", tt.input, p.Warnings())
+		}
+	}
+}
+
+func TestMatchWarnings(t *testing.T) {
+	tests := []struct {
+		input    string
+		warnings []string
+	}{
+		{"match x { 0 => a, _ => b }", nil},
+		{"match x { 0 => a, 1 => b }", []string{"1:1: match has no wildcard arm"}},
+		{"let y = match x { _ if x > 0 => a, n => b }", nil},
+		{"match 1 { x => x }", nil},
+		{"let y = match x { n if n > 0 => a, [n] => b }", []string{"1:9: match has no wildcard arm"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+		checkParserErrors(t, p)
+
+		warnings := p.Warnings()
+		if len(warnings) != len(tt.warnings) {
+			t.Fatalf("wrong number of warnings for %q. expected=%d, got=%d (%v)",
+				tt.input, len(tt.warnings), len(warnings), warnings)
+		}
+		for i, w := range tt.warnings {
+			if warnings[i] != w {
+				t.Errorf("wrong warning. expected=%q, got=%q", w, warnings[i])
+			}
+		}
+	}
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = arr;", "let [a, b] = arr;"},
+		{"let [a, b, ...rest] = arr;", "let [a, b, ...rest] = arr;"},
+		{"let [...all] = arr;", "let [...all] = arr;"},
+		{"let [[a, b], _] = arr;", "let [[a, b], _] = arr;"},
+		{"let {name, age} = person;", "let {name, age} = person;"},
+		{"let {} = person;", "let {} = person;"},
+		{"let [{x}, [y]] = pairs;", "let [{x}, [y]] = pairs;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Pattern == nil || stmt.Name != nil {
+			t.Fatalf("stmt is not a destructuring let. got Pattern=%v, Name=%v", stmt.Pattern, stmt.Name)
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestDestructuringLetErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let [a, ...rest, b] = arr;", "1:16: expected next token to be ], got , instead"},
+		{"let [a, ...] = arr;", "1:12: expected next token to be IDENT, got ] instead"},
+		{"let {name: n} = person;", "1:10: expected next token to be ,, got : instead"},
+		{`let {"name"} = person;`, "1:6: expected next token to be IDENT, got STRING instead"},
+		{"let [a b] = arr;", "1:8: expected next token to be ], got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"match x { a + 1 => b }", "1:13: expected next token to be =>, got + instead"},
+		{"match x { fn() {} => b }", "1:11: invalid pattern fn"},
+		{"match x { [a, 1 + 2] => b }", "1:17: expected next token to be ], got + instead"},
+		{"match x { 1 => a 2 => b }", "1:18: expected next token to be ,, got INT instead"},
+		{"match x { 1 => a,", "1:18: unterminated match expression"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
+	if s.TokenLiteral() != "let" {
+		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
+		return false
+	}
+
+	letStmt, ok := s.(*ast.LetStatement)
+	if !ok {
+		t.Errorf("s not *ast.LetStatement. got=%T", s)
+		return false
+	}
+
+	if letStmt.Name.Value != name {
+		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, letStmt.Name.Value)
+		return false
+	}
+
+	if letStmt.Name.TokenLiteral() != name {
+		t.Errorf("letStmt.Name.TokenLiteral() not '%s'. got=%s",
+			name, letStmt.Name.TokenLiteral())
+		return false
+	}
+
+	return true
+}
+
+func testInfixExpression(t *testing.T, exp ast.Expression, left interface{},
+	operator string, right interface{}) bool {
+
+	opExp, ok := exp.(*ast.InfixExpression)
+	if !ok {
+		t.Errorf("exp is not ast.InfixExpression. got=%T(%s)", exp, exp)
+		return false
+	}
+
+	if !testLiteralExpression(t, opExp.Left, left) {
+		return false
+	}
+
+	if opExp.Operator != operator {
 		t.Errorf("exp.Operator is not '%s'. got=%q", operator, opExp.Operator)
 		return false
 	}
@@ -890,3 +1998,159 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func TestTryExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`throw "boom";`, `throw "boom";`},
+		{"throw x + 1", "throw (x + 1);"},
+		{"try { f() } catch (e) { e }", "try f() catch (e) e"},
+		{"try { f() } catch { 0 }", "try f() catch 0"},
+		{"try { f() } finally { g() }", "try f() finally g()"},
+		{"try { f() } catch (e) { 0 } finally { g() }", "try f() catch (e) 0 finally g()"},
+		{"let x = try { f() } catch (e) { 0 };", "let x = try f() catch (e) 0;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("try { f() } catch (e) { 0 } finally { g() }")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	te, ok := stmt.Expression.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, te.Param, "e")
+	if te.Catch == nil || te.Finally == nil {
+		t.Errorf("try expression is missing a clause. got=%q", te.String())
+	}
+
+	errorTests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"try { f() }", "1:12: expected catch or finally after try, got EOF"},
+		{"try { f() } catch (1) { }", "1:20: expected next token to be IDENT, got INT instead"},
+		{"try f()", "1:5: expected next token to be {, got IDENT instead"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser error for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestDeferStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn() { defer close(f); }", "fn() defer close(f);"},
+		{"fn f() { defer log(\"done\") }", "fn f() defer log(\"done\");"},
+		{"fn() { while (true) { defer g() } }", "fn() whiletrue defer g();"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"defer f();", "1:1: defer outside of a function"},
+		{"let f = fn() { 1 }; if (true) { defer f() }", "1:33: defer outside of a function"},
+		{"fn() { defer; }", "1:13: no prefix parse function for ; found"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser error for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestPropagateExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn() { f(x)? }", "fn() (f(x)?)"},
+		{"fn f(s) { let n = parse(s)?; n }", "fn f(s) let n = (parse(s)?);n"},
+		{"fn() { if (true) { g()? } }", "fn() iftrue (g()?)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"f(x)?;", "1:5: ? outside of a function"},
+		{"let f = fn() { 1 }; let x = f()?;", "1:32: ? outside of a function"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser error for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
@@ -34,6 +34,9 @@ func Start(in io.Reader, out io.Writer) {
 			printParserErrors(out, p.Errors())
 			continue
 		}
+		for _, msg := range p.Warnings() {
+			io.WriteString(out, "warning: "+msg+"\n")
+		}
 
 		evaluated := evaluator.Eval(program, env)
 		if evaluated != nil && evaluated.Inspect() != "" {
@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 // TokenType is the type of token
 type TokenType string
 
@@ -8,10 +10,12 @@ const (
 	EOF     = "EOF"
 
 	// Identifiers & literals
-	IDENT   = "IDENT" // add, foobar, x, y, ...
-	INT     = "INT"   //18636463
-	STRING  = "STRING"
-	COMMENT = "COMMENT"
+	IDENT       = "IDENT" // add, foobar, x, y, ...
+	INT         = "INT"   //18636463
+	FLOAT       = "FLOAT" // 1.5, 1e-3
+	STRING      = "STRING"
+	STRING_PART = "STRING_PART" // "text up to a ${
+	COMMENT     = "COMMENT"
 
 	// Operators
 	ASSIGN   = "="
@@ -20,6 +24,20 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
+	POWER    = "**"
+
+	AMPERSAND   = "&"
+	PIPE        = "|"
+	CARET       = "^"
+	TILDE       = "~"
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
+
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
 
 	LT    = "<"
 	GT    = ">"
@@ -29,9 +47,18 @@ const (
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	AND = "&&"
+	OR  = "||"
+
+	ARROW    = "=>"
+	ELLIPSIS = "..."
+	QUESTION = "?"
+
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	DOT       = "."
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -48,22 +75,78 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	NOT      = "NOT"
+	SWITCH   = "SWITCH"
+	CASE     = "CASE"
+	DEFAULT  = "DEFAULT"
+	MATCH    = "MATCH"
+	IMPORT   = "IMPORT"
+	STRUCT   = "STRUCT"
+	ENUM     = "ENUM"
+	THROW    = "THROW"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	DEFER    = "DEFER"
 )
 
+// Position is a location in a source file
+type Position struct {
+	File   string
+	Line   int // 1-based line number
+	Column int // 1-based column number
+}
+
+// IsValid reports whether the position has line information
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+// String returns the position as file:line:column
+func (p Position) String() string {
+	if !p.IsValid() {
+		return p.File
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
 // Token is a language token
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
 }
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"not":      NOT,
+	"switch":   SWITCH,
+	"case":     CASE,
+	"default":  DEFAULT,
+	"match":    MATCH,
+	"import":   IMPORT,
+	"struct":   STRUCT,
+	"enum":     ENUM,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"defer":    DEFER,
 }
 
 // LookupIdent checks the keywords table to see if